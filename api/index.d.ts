@@ -19,7 +19,10 @@
 */
 export interface MockOptions {
   /**
-   * True value indicates synchronous mode operation. You should use it for synchronous k6 http API.
+   * True value indicates synchronous mode operation, false value indicates asynchronous mode operation.
+   * If not given (the default), the mode is decided per request: handlers run synchronously while the VU is blocked
+   * in a synchronous k6 http API call, otherwise they are scheduled on the event loop. In this mode an exception
+   * thrown by a handler is logged and the request is answered with 500, the iteration is not aborted.
    */
   sync: boolean
 
@@ -27,6 +30,104 @@ export interface MockOptions {
    * True value indicaes that given mock definition should be ignored.
    */
   skip: boolean
+
+  /**
+   * True value indicates that requests without matching route get a JSON diagnostic response body
+   * with the closest routes (with 404 status code).
+   */
+  diagnose: boolean
+
+  /**
+   * True value indicates that requests without matching route fail: the synchronous k6 http API call sending
+   * the request throws an error. The request gets the JSON diagnostic response body (with 404 status code) and
+   * is logged as error. Unmatched requests sent by `http.asyncRequest` are answered and counted the same way, without error.
+   */
+  strict: boolean
+
+  /**
+   * Delay of every response of the mock server in milliseconds, default is no delay.
+   */
+  delay: number
+
+  /**
+   * Port number of the mock server. Without `port` and `basePort` a random port is used.
+   * Mock definition fails if the port is already in use (e.g. the same port is used by more VUs).
+   */
+  port: number
+
+  /**
+   * Base port number of the mock server. The port number of the mock server will be `basePort + __VU`,
+   * so every VU gets its own port. Cannot be used together with `port`.
+   */
+  basePort: number
+
+  /**
+   * Listen address (host name or IP address) of the mock server, default is `127.0.0.1`.
+   * Use `0.0.0.0` to make the mock server available from other hosts.
+   */
+  host: string
+
+  /**
+   * Listen on Unix domain socket instead of TCP port. Use `true` for a generated socket path in the temporary
+   * directory, or the socket path as string. The request URL is kept unchanged, requests are sent to the
+   * socket by the transport of the VU. The application is served on the socket only, no TCP port is used.
+   * Cannot be used together with `port`, `basePort` and `host`.
+   */
+  socket: boolean | string
+
+  /**
+   * True value indicates that requests are sent to the mock server through in-memory connections, without
+   * listening on TCP port or Unix domain socket. The request URL is kept unchanged and k6 http metrics are
+   * emitted as usual. The Express like application is called in-process, no network connection is used at all.
+   * Cannot be used together with `port`, `basePort`, `host` and `socket`.
+   */
+  memory: boolean
+
+  /**
+   * HTTP/2 protocol of the mock server: `true` or `"h2"` for HTTP/2 over TLS (with generated self-signed
+   * certificate), `"h2c"` for HTTP/2 over cleartext TCP. The request URL is kept unchanged, so `res.proto`
+   * and `res.url` are the same as with real HTTP/2 hosts. Can be combined with the other listener options.
+   */
+  http2: boolean | "h2" | "h2c"
+
+  /**
+   * True value indicates HTTPS: the mock server talks HTTP/1.1 over TLS (with generated self-signed certificate),
+   * or HTTP/2 over TLS together with `http2`. Like with `http2`, the request URL is kept unchanged.
+   * Cannot be used together with `"h2c"`.
+   */
+  tls: boolean
+
+  /**
+   * Lifetime of the mock definition. The default `"vu"` scoped mock lives until `unmock` (or `mock.reset`) is called
+   * or the test ends. The `"iteration"` scoped mock is removed automatically at the end of the iteration,
+   * so it can be defined only in VU code.
+   */
+  scope: "vu" | "iteration"
+
+  /**
+   * Name of the mock definition, default is the target. The name can be used instead of the target
+   * in `unmock`, `mock.enable`, `mock.disable` and in the `mock` field of request params.
+   */
+  name: string
+
+  /**
+   * Size limit of form request bodies (`application/x-www-form-urlencoded` and `multipart/form-data`) in bytes,
+   * default is 10 MiB. Larger requests are rejected with 413 status code before reaching the route handler.
+   */
+  bodyLimit: number
+
+  /**
+   * Size limit of files uploaded in `multipart/form-data` request body in bytes, default is the body limit.
+   * Requests with larger files are rejected with 413 status code before reaching the route handler.
+   */
+  fileLimit: number
+
+  /**
+   * Verification of the JWT bearer tokens exposed as `req.jwt`. Keys are given by `key` (HMAC secret, PEM encoded
+   * public key or certificate, or JWK) and by `jwks` (JWKS object or `mock.oauth2` provider). The `iss` and `aud` claims
+   * are checked if `issuer` and `audience` are given, `exp` and `nbf` claims are always checked.
+   */
+  jwt: { key?: string | Record<string, any>; jwks?: Record<string, any> | OAuth2Provider; issuer?: string; audience?: string }
 }
 
 /**
@@ -36,7 +137,13 @@ export interface MockOptions {
  * callback function for programming. After mock programming is done, new mock HTTP server will be start.
  * Whan you use http API from mock module, all matching URLs will be directed to this mock server.
  * 
- * You can create as many mock definitions (server) as you want.
+ * You can create as many mock definitions (server) as you want. Calling `mock` again with the same target
+ * replaces the previous mock definition. Mock servers of all VUs are shut down gracefully at the end of the test,
+ * after `handleSummary` (which can still send requests to them).
+ *
+ * Mocks can be used in `setup()` and `teardown()` too. In the VU running these functions (`__VU` is 0)
+ * the mock server is started lazily, on the first request to its target, so no servers are started
+ * while k6 is parsing the options.
  * 
  * You can disable the given mock definition quickly by passing options parameter with `skip` set to true.
  * ```JavaScript
@@ -60,9 +167,40 @@ export interface MockOptions {
  * })
  * ```
  * 
+ * The `K6_MOCK` environment variable selects the active mock definitions without editing the script:
+ * `all` (default), `none`, or a comma separated list of names or targets, which may contain `*` and `?` wildcards
+ * (e.g. `k6 run -e K6_MOCK='api,https://*.example.com' script.js`). Other mock definitions are skipped,
+ * and `mock.enable`, `mock.disable` and `{ mock: 'name' }` request params referring to them are ignored.
+ * 
+ * Mocking can be overridden for a single request with the `mock` field of the request params
+ * (it is removed before the request is made):
+ *  - `{ mock: false }` sends the request to the real host, even if there is a matching mock
+ *  - `{ mock: 'name' }` sends the request to the mock with the given name (or target), even if another (longer) target
+ *    matches too or the mock is disabled
+ * ```JavaScript
+ * http.get('https://example.com/health', { mock: false })
+ * ```
+ *
+ * Mock servers emit the following metrics, tagged with `target`, `method`, `status` and
+ * (for matching requests) `route`:
+ *
+ *  - `mock_requests` (counter): number of requests served by mock servers
+ *  - `mock_unmatched_requests` (counter): number of requests without matching route
+ *  - `mock_handler_duration` (trend): time spent serving the requests
+ *
+ * For example the `mock_unmatched_requests: ['count==0']` threshold fails the test if any request missed the routes.
+ *
+ * Requests without matching route are logged as warnings together with the closest routes
+ * (method mismatch, mistyped, missing or extra path segment, trailing slash mismatch).
+ * A summary of these requests is logged at the end of the test.
+ *
+ * The k6 http metrics of mocked requests are tagged with `mocked` (value `true`) and `mock_target` (the mock target).
+ * Unless the `name` tag is set in request params, the `name` and `url` tags refer to the original URL,
+ * not to the URL of the mock server.
+ *
  * @param target the URL or URL prefix to be mocked
  * @param callback function to for defining route definitions for mock server
- * @param options optional flags (`sync`, `skip`)
+ * @param options optional flags and settings (`sync`, `skip`, `diagnose`, `strict`, `delay`, `port`, `basePort`, `host`, `socket`, `memory`, `http2`, `tls`, `scope`, `name`)
  */
 export function mock(target: String, callback: (app: Application) => void, options?: MockOptions): void;
 
@@ -71,10 +209,437 @@ export function mock(target: String, callback: (app: Application) => void, optio
  * 
  * This function will remove mock definition associated to given URL and stop the related HTTP server.
  * 
- * @param target the URL or URL prefix of mock definition to be remove
+ * @param target the URL or URL prefix (or the name) of mock definition to be remove
  */
 export function unmock(target: String): void;
 
+/**
+ * Helper functions attached to the `mock` function.
+ */
+export declare namespace mock {
+  /**
+   * Fake data generator for mock responses.
+   *
+   * The generator is seeded once per VU. Setting the `K6_MOCK_SEED` environment variable
+   * makes the generated data reproducible between runs (the VU number is added to the seed).
+   *
+   * k6 has no run-wide seed option: `randomSeed()` of the `k6` module reseeds only `Math.random` of the VU,
+   * and the seed cannot be read back. Use `K6_MOCK_SEED` or {@link Faker.seed} together with it.
+   *
+   * The module has no response templates: responses are built by the route handlers, which call the generator.
+   *
+   * @example
+   * mock('https://example.com', app => {
+   *   app.get('/user', (req, res) => {
+   *     res.json({ id: mock.faker.uuid(), name: mock.faker.name(), email: mock.faker.email() })
+   *   })
+   * })
+   */
+  const faker: Faker;
+
+  /**
+   * Create a middleware that validates the request body against a JSON Schema (draft 2020-12).
+   *
+   * Invalid requests are rejected with the given status (default 400) and a JSON body in JSON Schema
+   * "basic" output format: `{ valid: false, errors: [{ keywordLocation, instanceLocation, error }] }`.
+   * Every rejected request increments the `mock_schema_violations` counter metric, so a threshold
+   * like `mock_schema_violations: ['count==0']` can fail the test run.
+   *
+   * A subset of draft 2020-12 is supported:
+   *  - `type`, `enum`, `const`
+   *  - `minimum`, `maximum`, `exclusiveMinimum`, `exclusiveMaximum`, `multipleOf`
+   *  - `minLength`, `maxLength`, `pattern`, `format`
+   *  - `items`, `prefixItems`, `contains`, `minContains`, `maxContains`, `minItems`, `maxItems`, `uniqueItems`
+   *  - `properties`, `patternProperties`, `additionalProperties`, `propertyNames`, `required`,
+   *    `minProperties`, `maxProperties`, `dependentRequired`, `dependentSchemas`
+   *  - `allOf`, `anyOf`, `oneOf`, `not`, `if`, `then`, `else`
+   *  - `$ref` to local references only (`#`, `#/$defs/...`), `$defs`, and `$id` of the root schema
+   *
+   * Common `format` values (`date-time`, `date`, `time`, `email`, `uuid`, `uri`, `ipv4`, `ipv6`, `hostname`, `regex`)
+   * are asserted. Schemas using `unevaluatedProperties`, `unevaluatedItems`, `$anchor`, `$dynamicRef`,
+   * `$dynamicAnchor`, `$id` in subschemas, non-local `$ref`, or the draft-07 forms of `items`, `additionalItems`
+   * and `dependencies` are rejected when the schema is loaded, instead of passing requests they should reject.
+   *
+   * @example
+   * mock('https://example.com', app => {
+   *   app.post('/users', mock.validate('./user.schema.json', { status: 422 }), (req, res) => {
+   *     res.json({ id: mock.faker.uuid(), name: req.body.name })
+   *   })
+   * })
+   *
+   * @param schema the schema object or the path of a JSON file containing the schema (file loading works only in init context)
+   * @param options optional settings, `status` is the response status code for invalid requests
+   */
+  function validate(schema: Record<string, any> | boolean | string, options?: { status?: number }): Middleware;
+
+  /**
+   * Create a middleware that enables CORS (Cross-Origin Resource Sharing) on the application.
+   *
+   * Preflight requests (`OPTIONS` with `Access-Control-Request-Method` header) are answered by the middleware,
+   * even if there is no `OPTIONS` route for the path. Other responses get the `Access-Control-Allow-Origin`,
+   * `Access-Control-Allow-Credentials` and `Access-Control-Expose-Headers` headers, if the origin is allowed.
+   * Use it with `app.use`, so it runs for preflight requests too. It also works with `Application` instances
+   * outside of `mock`; in a mock definition the unmatched request responses of the mock server get the CORS
+   * headers too. Use it before the authentication middlewares, so their failure responses get the headers.
+   *
+   * @example
+   * mock('https://api.example.com', app => {
+   *   app.use(mock.cors({ origin: ['https://app.example.com', 'http://localhost:*'], credentials: true }))
+   *   app.get('/items', (req, res) => res.json([]))
+   * })
+   *
+   * @param options optional settings (all origins and the common methods are allowed by default)
+   */
+  function cors(options?: CORSOptions): Middleware;
+
+  /**
+   * Authentication middlewares. Requests are checked where the middleware is in the middleware chain,
+   * using the raw request (so signatures can be verified). Failed requests get a 401 or 403 response
+   * with `WWW-Authenticate` header (except AWS SigV4, which responds like AWS: 403 with XML error body),
+   * and increment the `mock_auth_failures` counter metric (tagged with `scheme`, `target`, `method`, `status`).
+   *
+   * A middleware can be used for all routes with `app.use` or for a single route. It also works with
+   * `Application` instances outside of `mock`, but only requests of mock definitions increment the metric.
+   *
+   * @example
+   * mock('https://api.example.com', app => {
+   *   app.use(mock.auth.apiKey({ keys: ['k1'], header: 'X-API-Key' }))
+   *   app.get('/admin', mock.auth.bearer({ tokens: { t1: 'admin' }, scope: 'admin' }), (req, res) => res.text('ok'))
+   * })
+   */
+  const auth: {
+    /** HTTP Basic authentication, `users` maps user names to passwords. */
+    basic(options: { users: Record<string, string>; realm?: string }): Middleware;
+    /** Bearer tokens, given as list or as object mapping tokens to granted scopes. Missing scopes are rejected with 403. */
+    bearer(options: { tokens: string[] | Record<string, string | string[]>; scope?: string | string[]; realm?: string }): Middleware;
+    /** API keys in a request header (default `X-API-Key`) or in a query parameter. Invalid keys are rejected with 403. */
+    apiKey(options: { keys: string | string[]; header?: string; query?: string; realm?: string }): Middleware;
+    /**
+     * HMAC signature of the request body in a request header (default `X-Signature`), e.g. webhook signatures
+     * (`{ header: 'X-Hub-Signature-256', prefix: 'sha256=' }`).
+     */
+    hmac(options: {
+      secret: string;
+      header?: string;
+      prefix?: string;
+      algorithm?: "sha1" | "sha256" | "sha512";
+      encoding?: "hex" | "base64";
+    }): Middleware;
+    /**
+     * AWS Signature Version 4 (`Authorization` header). Credentials are given by `accessKeyId` and `secretAccessKey`,
+     * or by `keys` mapping access key ids to secret keys. Region and service of the credential scope are checked if given.
+     * The `X-Amz-Content-Sha256` header must match the body (400 `XAmzContentSHA256Mismatch`), unless it is `UNSIGNED-PAYLOAD`.
+     */
+    sigv4(options: {
+      accessKeyId?: string;
+      secretAccessKey?: string;
+      keys?: Record<string, string>;
+      region?: string;
+      service?: string;
+    }): Middleware;
+  };
+
+  /**
+   * Define a mock OAuth2 authorization server and OpenID Connect provider on the target (which is also the issuer).
+   *
+   * The provider serves the discovery document (`/.well-known/openid-configuration`), the JWKS (`/.well-known/jwks.json`),
+   * the token endpoint (`/token`) and the userinfo endpoint (`/userinfo`). The token endpoint supports
+   * `client_credentials`, `password` and `refresh_token` grants, clients authenticate with HTTP Basic authentication
+   * or with `client_id` and `client_secret` form parameters. Access tokens (and ID tokens, if `openid` scope is granted)
+   * are JWTs signed with RS256. Refresh tokens are issued for users only, and can be used only once.
+   *
+   * The signing key and the refresh tokens are shared by VUs, so tokens obtained in `setup` are accepted in all VUs.
+   * Mock options (e.g. `name`, `memory`) can be given among the provider options.
+   *
+   * @example
+   * const idp = mock.oauth2('https://idp.example.com', {
+   *   clients: { service: { secret: 's3cret', scopes: ['read', 'write'] }, spa: '' },
+   *   users: { alice: { password: 'pw', email: 'alice@example.com' } },
+   * })
+   *
+   * mock('https://api.example.com', app => {
+   *   app.get('/items', idp.auth({ scope: 'read' }), (req, res) => res.json([]))
+   * })
+   *
+   * @param target the issuer URL of the provider
+   * @param options clients, users and scopes of the provider
+   * @returns the provider, its `auth` method creates a middleware accepting the access tokens of the provider
+   */
+  function oauth2(target: string, options?: OAuth2Options): OAuth2Provider;
+
+  /**
+   * JSON Web Token helpers.
+   */
+  const jwt: {
+    /**
+     * Create a signed JWT (compact serialization) from the claims. The algorithm is chosen by the key:
+     * HS256 for secret string, RS256 or ES256 (P-256 curve) for PEM encoded private key (PKCS #1, PKCS #8 or SEC 1)
+     * and RS256 for `mock.oauth2` provider (the token is signed like the tokens issued by the provider).
+     *
+     * @example
+     * const exp = Math.floor(Date.now() / 1000) + 3600
+     * const token = mock.jwt.sign({ sub: 'alice', scope: 'read', exp }, 'secret')
+     *
+     * @param claims the claims of the token
+     * @param key the signing key
+     * @param options `kid` sets the key id header
+     */
+    sign(claims: Record<string, any>, key: string | OAuth2Provider, options?: { kid?: string }): string;
+  };
+
+  /**
+   * Route coverage report of mock definitions, aggregated across all VUs.
+   *
+   * Every route registered in mock applications is listed with its hit count and response status distribution,
+   * so routes never exercised by the test are easy to spot.
+   * The report can be merged into `handleSummary` data or written as JSON file.
+   * Alternatively, setting the `K6_MOCK_COVERAGE` environment variable to a file name
+   * writes the report as JSON file at the end of the test.
+   *
+   * @example
+   * export function handleSummary(data) {
+   *   return { 'mock-coverage.json': JSON.stringify(mock.coverage(), null, 2) }
+   * }
+   */
+  function coverage(): CoverageReport;
+
+  /**
+   * Remove all mock definitions of the VU and stop the related HTTP servers.
+   */
+  function reset(): void;
+
+  /**
+   * List the targets of the mock definitions of the VU.
+   *
+   * @returns the mock targets in alphabetical order
+   */
+  function list(): string[];
+
+  /**
+   * Suspend mocking: requests matching the mock definition are sent to the real host.
+   * The mock server keeps running with its state, until the mock definition is enabled again.
+   *
+   * @example
+   * mock('https://example.com', callback, { name: 'api' })
+   *
+   * export default function () {
+   *   mock.disable('api')
+   *   http.get('https://example.com') // real backend
+   *   mock.enable('api')
+   *   http.get('https://example.com') // mocked backend
+   * }
+   *
+   * @param name the name (or the target) of the mock definition
+   */
+  function disable(name: string): void;
+
+  /**
+   * Resume mocking of a disabled mock definition.
+   *
+   * @param name the name (or the target) of the mock definition
+   */
+  function enable(name: string): void;
+
+  /**
+   * Register mock targets as request interception rules of a browser page (or browser context).
+   *
+   * The `route` method of the page is called with a pattern for each mock definition.
+   * Page requests to mocked targets are fulfilled by the mock servers, disabled mocks let the requests continue.
+   * Mock definitions created after calling `intercept` are not registered.
+   *
+   * The page must support request interception (`page.route`), which depends on the version of k6 browser module;
+   * pages without it are rejected with an error. The handlers return the promise of `route.fulfill` (or `route.continue`),
+   * so the browser waits for them like for async handlers. Multiple values of a response header are joined
+   * (`Set-Cookie` values by newlines).
+   *
+   * @example
+   * import { browser } from 'k6/experimental/browser'
+   *
+   * mock('https://cdn.example.com', app => {
+   *   app.get('/lib.js', (req, res) => res.text('window.lib = {}'))
+   * })
+   *
+   * export default async function () {
+   *   const page = browser.newPage()
+   *
+   *   mock.intercept(page)
+   *
+   *   await page.goto('https://example.com')
+   * }
+   *
+   * @param page the browser page or browser context
+   */
+  function intercept(page: { route(url: RegExp, handler: (route: any) => Promise<void>): any }): void;
+}
+
+/**
+ * Settings of `mock.cors` middleware. Lists can be given as arrays or comma separated strings.
+ */
+export interface CORSOptions {
+  /** Allowed origins, `*` wildcard can be used (e.g. `https://*.example.com`), default is any origin. */
+  origin?: string | string[];
+  /** Methods allowed in preflight responses, default is `GET, HEAD, PUT, PATCH, POST, DELETE`. */
+  methods?: string | string[];
+  /** Headers allowed in preflight responses, default is the requested headers. */
+  allowedHeaders?: string | string[];
+  /** Response headers exposed to the browser. */
+  exposedHeaders?: string | string[];
+  /** True value allows credentials (the request origin is sent back instead of `*`). */
+  credentials?: boolean;
+  /** Number of seconds the preflight response can be cached. */
+  maxAge?: number;
+}
+
+/**
+ * Settings of `mock.oauth2` provider. Scope lists can be given as arrays or space (or comma) separated strings.
+ */
+export interface OAuth2Options extends MockOptions {
+  /** Clients by client id, given as secret (empty for public clients) or as object with secret and allowed scopes. */
+  clients?: Record<string, string | { secret?: string; scopes?: string | string[] }>;
+  /** Users of the `password` grant, given as password or as object with password, other properties are user claims. */
+  users?: Record<string, string | ({ password: string } & Record<string, any>)>;
+  /** Scopes supported by the provider, allowed for clients without own scopes (default is any scope). */
+  scopes?: string | string[];
+  /** Lifetime of the issued tokens in seconds, default is 3600. */
+  expiresIn?: number;
+}
+
+/**
+ * Mock OAuth2/OIDC provider.
+ */
+export interface OAuth2Provider {
+  /** Issuer URL of the provider (the `iss` claim of the issued tokens). */
+  issuer: string;
+
+  /**
+   * Create a middleware accepting the access tokens issued by the provider (`Authorization: Bearer` header).
+   * Missing, invalid or expired tokens are rejected with 401, tokens without the required scopes with 403.
+   * Failures increment the `mock_auth_failures` metric with `oauth2` scheme tag.
+   */
+  auth(options?: { scope?: string | string[]; audience?: string; realm?: string }): Middleware;
+}
+
+/**
+ * Route coverage report.
+ */
+export interface CoverageReport {
+  /** Number of registered routes. */
+  routes: number;
+
+  /** Number of routes hit at least once. */
+  hit: number;
+
+  /** Ratio of hit routes (between 0 and 1). */
+  coverage: number;
+
+  /** Coverage of mock definitions, sorted by target. */
+  mocks: Array<{
+    target: string;
+    routes: number;
+    hit: number;
+    coverage: number;
+    /** Routes with hit count and response count by status code. */
+    entries: Array<{ method: string; path: string; hits: number; statuses: Record<string, number> }>;
+  }>;
+}
+
+/**
+ * Fake data generator functions.
+ */
+export interface Faker {
+  /**
+   * Reseed the generator.
+   *
+   * @param seed the new seed value
+   */
+  seed(seed: number): void;
+
+  /** Random (version 4) UUID string. */
+  uuid(): string;
+
+  /** First name. */
+  firstName(): string;
+
+  /** Last name. */
+  lastName(): string;
+
+  /** Full name (first and last name). */
+  name(): string;
+
+  /** E-mail address. */
+  email(): string;
+
+  /** Street address line (house number and street name). */
+  street(): string;
+
+  /** City name. */
+  city(): string;
+
+  /** Five digit postal code. */
+  zip(): string;
+
+  /** Country name. */
+  country(): string;
+
+  /** Address object with `street`, `city`, `zip` and `country` properties. */
+  address(): Record<string, string>;
+
+  /**
+   * Lorem ipsum words separated by space.
+   *
+   * @param count number of words (default 8)
+   */
+  words(count?: number): string;
+
+  /**
+   * Lorem ipsum sentence.
+   *
+   * @param count number of words (default 8)
+   */
+  sentence(count?: number): string;
+
+  /**
+   * Lorem ipsum paragraph.
+   *
+   * @param count number of sentences (default 4)
+   */
+  paragraph(count?: number): string;
+
+  /**
+   * Date and time in RFC 3339 format between `from` and `to` (default between 2000 and 2030).
+   *
+   * @param from lower bound (Date or date string)
+   * @param to upper bound (Date or date string)
+   */
+  date(from?: Date | string, to?: Date | string): string;
+
+  /**
+   * Integer number between `min` and `max` (inclusive). Called with one parameter the range is `0..max`.
+   *
+   * @param min lower bound
+   * @param max upper bound
+   */
+  int(min?: number, max?: number): number;
+
+  /**
+   * Floating point number between `min` and `max`. Called with one parameter the range is `0..max`.
+   *
+   * @param min lower bound
+   * @param max upper bound
+   */
+  float(min?: number, max?: number): number;
+
+  /** Random boolean value. */
+  bool(): boolean;
+
+  /**
+   * Random element of the given array, `undefined` if the array is empty (or its `length` is not positive).
+   *
+   * @param from the array to pick from
+   */
+  pick<T>(from: T[]): T;
+}
+
 // muxpress ------------------------------------------------------------------------
 
 /**
@@ -107,8 +672,10 @@ export type Middleware = (req: Request, res: Response, next: () => void) => void
 export class Application {
   /**
    * Creates a new application instance.
+   *
+   * @param options optional flags and settings (`sync`, `bodyLimit`, `fileLimit`)
    */
-  constructor();
+  constructor(options?: MockOptions);
 
   /**
    * Routes HTTP GET requests to the specified path with the specified middleware functions.
@@ -206,6 +773,20 @@ export class Application {
   listen(addr?: string, callback?: () => void): void;
 }
 
+/**
+ * File uploaded in `multipart/form-data` request body.
+ */
+export interface UploadedFile {
+  /** File name given by the client. */
+  filename: string;
+  /** Content type of the file, default is `application/octet-stream`. */
+  contentType: string;
+  /** Size of the file in bytes. */
+  size: number;
+  /** Content of the file. */
+  data: ArrayBuffer;
+}
+
 /**
  * The `req` object represents the HTTP request and has properties for the request query string, parameters, body, HTTP headers, and so on.
  *
@@ -221,15 +802,35 @@ export interface Request {
   /**
    * Contains key-value pairs of data submitted in the request body.
    * By default, it is undefined, and is populated when the request
-   * Content-Type is `application/json`.
+   * Content-Type is `application/json`, `application/x-www-form-urlencoded` or `multipart/form-data`.
+   * Form fields are strings, fields given more than once are arrays of strings.
    */
   body: Record<string, any> | undefined;
 
+  /**
+   * Contains the files uploaded in `multipart/form-data` request body, by field name.
+   * Files uploaded more than once with the same field name are arrays.
+   * It is undefined for other requests.
+   *
+   * @example
+   * app.post("/upload", (req, res) => {
+   *   res.json({ name: req.files.file.filename, size: req.files.file.size });
+   * });
+   */
+  files: Record<string, UploadedFile | UploadedFile[]> | undefined;
+
   /**
    * This property is an object that contains cookies sent by the request.
    */
   cookies: Record<string, string>;
 
+  /**
+   * Contains the claims of the JWT bearer token (`Authorization: Bearer` header) of the request, or null if there is no
+   * such token. Without `jwt` mock option, the token is only parsed. With `jwt` mock option, the token is verified
+   * (signature, issuer, audience, expiry) and tokens failed to verify are ignored (the property is null).
+   */
+  jwt: Record<string, any> | null;
+
   /**
    * Contains a string corresponding to the HTTP method of the request: GET, POST, PUT, and so on.
    */
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	_ "embed"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+var httpMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// NewApplicationConstructor creates an application constructor function. The returned constructor function is ready for use and assignable to any name in a given [sobek.Runtime].
+// This allow to decide the name of the JavaScript constructor.
+// You can pass [Option] parameters to customize the muxpress runtime behavior.
+func NewApplicationConstructor(runtime *sobek.Runtime, option ...Option) (func(call sobek.ConstructorCall) *sobek.Object, error) {
+	opts, err := getopts(option...)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(call sobek.ConstructorCall) *sobek.Object {
+		return wrapApplication(runtime, call.This, newApplication(opts))
+	}, nil
+}
+
+// Handler serves the requests of an application in-process.
+type Handler interface {
+	http.Handler
+
+	// Shutdown stops the application's server if listen was called. It does not use the [sobek.Runtime],
+	// so it can be called from any goroutine.
+	Shutdown()
+}
+
+// NewApplication creates an application object like the application constructor does, and returns it
+// together with its [Handler]. The handler serves requests in-process, so the host embedding muxpress
+// can use its own listener and wrap the handler, without calling listen.
+// You can pass [Option] parameters to customize the muxpress runtime behavior.
+func NewApplication(runtime *sobek.Runtime, option ...Option) (*sobek.Object, Handler, error) {
+	opts, err := getopts(option...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	app := newApplication(opts)
+
+	return wrapApplication(runtime, runtime.NewObject(), app), app, nil
+}
+
+func wrapApplication(runtime *sobek.Runtime, this *sobek.Object, app *application) *sobek.Object {
+	for _, method := range httpMethods {
+		mustSet(runtime, this, strings.ToLower(method), app.handlerFor(runtime, strings.ToUpper(method)))
+	}
+
+	mustSet(runtime, this, "static", app.static)
+
+	mustSet(runtime, this, "use", app.use)
+	mustSet(runtime, this, "listen", app.listen)
+	mustSet(runtime, this, "shutdown", app.shutdown)
+
+	mustSetGetter(runtime, this, "host", app.host)
+	mustSetGetter(runtime, this, "hostname", app.hostname)
+	mustSetGetter(runtime, this, "port", app.port)
+
+	// OPTIONS requests without OPTIONS route (e.g. CORS preflight) are passed to the application
+	// level middlewares, the Allow header is already set by the router
+	app.router.GlobalOPTIONS = http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		app.router.handle(runtime, response, request)
+	})
+
+	return this
+}
+
+type address struct {
+	host     string
+	hostname string
+	port     int
+}
+
+type application struct {
+	*router
+	server  *server
+	address *address
+}
+
+func newApplication(opts *options) *application {
+	app := new(application)
+
+	app.router = newRouter(opts.runner, opts.filesystem)
+	app.router.limits = opts.limits
+	app.server = newServer(opts.context, opts.logger)
+
+	return app
+}
+
+func (app *application) listen(call sobek.FunctionCall, runtime *sobek.Runtime) sobek.Value { // nolint:ireturn
+	args := call.Arguments
+	idx := 0
+	addr := new(address)
+
+	if len(args) > idx && args[idx].ExportType().Kind() == reflect.Int64 {
+		addr.port = int(args[idx].ToInteger())
+		idx++
+	}
+
+	if len(args) > idx && args[idx].ExportType().Kind() == reflect.String {
+		addr.hostname = args[idx].String()
+		idx++
+	}
+
+	addr.host = net.JoinHostPort(addr.hostname, strconv.Itoa(addr.port))
+
+	tcp, err := app.server.listenAndServe(addr.host, app.router)
+
+	must(runtime, err)
+
+	if addr.port == 0 {
+		addr.port = tcp.Port
+	}
+
+	if len(addr.hostname) == 0 {
+		addr.hostname = defaultHost
+	}
+
+	addr.host = net.JoinHostPort(addr.hostname, strconv.Itoa(addr.port))
+
+	app.address = addr
+
+	if len(args) > idx {
+		if callback, ok := sobek.AssertFunction(args[idx]); ok {
+			app.runner(func() error {
+				_, err := callback(runtime.GlobalObject())
+
+				return err
+			})
+		}
+	}
+
+	return nil
+}
+
+func (app *application) host(_ sobek.FunctionCall, runtime *sobek.Runtime) sobek.Value {
+	if app.address == nil {
+		return sobek.Null()
+	}
+
+	return runtime.ToValue(app.address.host)
+}
+
+func (app *application) hostname(_ sobek.FunctionCall, runtime *sobek.Runtime) sobek.Value {
+	if app.address == nil {
+		return sobek.Null()
+	}
+
+	return runtime.ToValue(app.address.hostname)
+}
+
+func (app *application) port(_ sobek.FunctionCall, runtime *sobek.Runtime) sobek.Value {
+	if app.address == nil {
+		return sobek.Null()
+	}
+
+	return runtime.ToValue(app.address.port)
+}
+
+func (app *application) shutdown(_ sobek.FunctionCall, runtime *sobek.Runtime) sobek.Value { // nolint:ireturn
+	app.server.shutdown()
+
+	return sobek.Undefined()
+}
+
+func (app *application) Shutdown() {
+	app.server.shutdown()
+}
+
+func (app *application) handlerFor(runtime *sobek.Runtime, method string) func(sobek.FunctionCall) sobek.Value {
+	return func(call sobek.FunctionCall) sobek.Value {
+		args := call.Arguments
+		idx := 0
+
+		var path string
+
+		if len(args) > idx {
+			path = call.Argument(idx).String()
+
+			idx++
+		}
+
+		middlewares := []middleware{}
+
+		for _, arg := range args[idx:] {
+			var m middleware
+
+			must(runtime, runtime.ExportTo(arg, &m))
+
+			middlewares = append(middlewares, m)
+		}
+
+		app.handleMethod(runtime, method, path, middlewares...)
+
+		return sobek.Undefined()
+	}
+}
+
+func (app *application) static(call sobek.FunctionCall, runtime *sobek.Runtime) sobek.Value {
+	args := call.Arguments
+	idx := 0
+
+	if len(args) <= idx {
+		throwf(runtime, "missing path parameter")
+	}
+
+	path := call.Argument(idx).String()
+
+	idx++
+
+	if len(args) <= idx {
+		throwf(runtime, "missing docroot parameter")
+	}
+
+	docroot := call.Argument(idx).String()
+
+	app.router.static(path, docroot)
+
+	return sobek.Undefined()
+}
+
+const defaultHost = "localhost"
+
+//go:embed api/index.d.ts
+
+// Declarations holds TypeScript declaration file contents for muxpress types.
+var Declarations []byte
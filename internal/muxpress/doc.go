@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+// Package muxpress provides Express.js like micro web framework for sobek.
+//
+// # Features
+//
+// Easy integration was the main design goal.
+// Major features:
+//
+//   - Express.js like JavaScript API
+//
+//   - Context-aware implementation
+//
+//   - Event loop ready, tested with goja_nodejs and k6 event loop
+//
+//   - Also works without event loop
+//
+// # Differences from upstream
+//
+// This is a copy of github.com/szkiba/muxpress v0.1.0, ported to sobek and extended for xk6-mock.
+// The upstream tests are kept. Changes:
+//
+//   - [NewApplication] returns an application together with its [Handler], so the application can be
+//     served in-process, wrapped by the mock server, instead of its own listener
+//
+//   - form request bodies are parsed into the body and files properties of requests, with size limits
+//     given by [WithFormLimits] or [WithFormLimitsFunc]
+//
+//   - OPTIONS requests without OPTIONS route (e.g. CORS preflight) are passed to the application level middlewares
+//
+//   - [RequestOf] gives the Go request of request objects to Go middlewares, the request body can be read
+//     again after the body property or the form parser read it
+//
+//   - an exception thrown by a middleware is answered with 500 and returned to the runner,
+//     the request does not hang if the runner recovers it
+//
+//   - shutdown of an application which is not listening is a no-op, instead of blocking forever
+package muxpress
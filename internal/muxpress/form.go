@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+
+	"github.com/grafana/sobek"
+)
+
+const (
+	mimeURLEncoded = "application/x-www-form-urlencoded"
+	mimeMultipart  = "multipart/form-data"
+
+	defaultBodyLimit = 10 << 20
+)
+
+var (
+	errBodyTooLarge = errors.New("request body too large")
+	errFileTooLarge = errors.New("uploaded file too large")
+)
+
+// formLimits are the size limits of form bodies in bytes. Zero body limit means the default,
+// zero file limit means the body limit.
+type formLimits struct {
+	body int64
+	file int64
+}
+
+// formFile is a file uploaded in multipart form.
+type formFile struct {
+	filename    string
+	contentType string
+	data        []byte
+}
+
+// form is a parsed form body.
+type form struct {
+	fields url.Values
+	files  map[string][]*formFile
+}
+
+// isForm reports whether the request body is URL encoded or multipart form.
+func isForm(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	return err == nil && (mediaType == mimeURLEncoded || mediaType == mimeMultipart)
+}
+
+// parseForm reads and parses the form body of the request.
+func parseForm(r *http.Request, limits formLimits) (*form, error) {
+	if limits.body <= 0 {
+		limits.body = defaultBodyLimit
+	}
+
+	if limits.file <= 0 || limits.file > limits.body {
+		limits.file = limits.body
+	}
+
+	if r.ContentLength > limits.body {
+		return nil, fmt.Errorf("%w: limit is %d bytes", errBodyTooLarge, limits.body)
+	}
+
+	defer r.Body.Close() // nolint:errcheck
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, limits.body+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > limits.body {
+		return nil, fmt.Errorf("%w: limit is %d bytes", errBodyTooLarge, limits.body)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType == mimeURLEncoded {
+		fields, err := url.ParseQuery(string(data))
+		if err != nil {
+			return nil, err
+		}
+
+		return &form{fields: fields, files: nil}, nil
+	}
+
+	return parseMultipart(multipart.NewReader(bytes.NewReader(data), params["boundary"]), limits.file)
+}
+
+// parseMultipart parses the multipart form. The size of fields is limited by the body limit only,
+// the whole body is already read.
+func parseMultipart(reader *multipart.Reader, fileLimit int64) (*form, error) {
+	result := &form{fields: make(url.Values), files: make(map[string][]*formFile)}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name := part.FormName()
+
+		if len(part.FileName()) == 0 {
+			data, err := io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+
+			result.fields.Add(name, string(data))
+
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, fileLimit+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(len(data)) > fileLimit {
+			return nil, fmt.Errorf("%w: %s exceeds the limit of %d bytes", errFileTooLarge, part.FileName(), fileLimit)
+		}
+
+		contentType := part.Header.Get("Content-Type")
+		if len(contentType) == 0 {
+			contentType = "application/octet-stream"
+		}
+
+		result.files[name] = append(result.files[name], &formFile{
+			filename:    part.FileName(),
+			contentType: contentType,
+			data:        data,
+		})
+	}
+}
+
+// formStatus returns the status code of the response rejecting the form body.
+func formStatus(err error) int {
+	if errors.Is(err, errBodyTooLarge) || errors.Is(err, errFileTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusBadRequest
+}
+
+// wrapFields returns the fields of the form, fields given more than once are arrays.
+func wrapFields(runtime *sobek.Runtime, parsed *form) sobek.Value {
+	return wrapValues(runtime, parsed.fields)
+}
+
+// wrapFiles returns the uploaded files of the form, files uploaded more than once are arrays.
+func wrapFiles(runtime *sobek.Runtime, parsed *form) *sobek.Object {
+	out := runtime.NewObject()
+
+	for name, list := range parsed.files {
+		objs := make([]interface{}, 0, len(list))
+
+		for _, file := range list {
+			obj := runtime.NewObject()
+
+			mustSet(runtime, obj, "filename", file.filename)
+			mustSet(runtime, obj, "contentType", file.contentType)
+			mustSet(runtime, obj, "size", len(file.data))
+			mustSet(runtime, obj, "data", runtime.NewArrayBuffer(file.data))
+
+			objs = append(objs, obj)
+		}
+
+		if len(objs) == 1 {
+			mustSet(runtime, out, name, objs[0])
+		} else {
+			mustSet(runtime, out, name, runtime.NewArray(objs...))
+		}
+	}
+
+	return out
+}
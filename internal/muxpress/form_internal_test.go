@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseForm(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("a=1&b=2&b=3"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	assert.True(t, isForm(r))
+
+	parsed, err := parseForm(r, formLimits{}) // nolint:exhaustruct
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "3"}, parsed.fields["b"])
+
+	// the body can be read again
+	data, err := io.ReadAll(r.Body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "a=1&b=2&b=3", string(data))
+
+	var buff bytes.Buffer
+
+	writer := multipart.NewWriter(&buff)
+
+	assert.NoError(t, writer.WriteField("title", "quarterly report"))
+
+	part, err := writer.CreateFormFile("file", "report.csv")
+
+	assert.NoError(t, err)
+
+	_, err = part.Write([]byte("a,b\n1,2\n"))
+
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(buff.Bytes()))
+		r.Header.Set("Content-Type", writer.FormDataContentType())
+
+		return r
+	}
+
+	parsed, err = parseForm(newRequest(), formLimits{}) // nolint:exhaustruct
+
+	assert.NoError(t, err)
+	assert.Equal(t, "quarterly report", parsed.fields.Get("title"))
+	assert.Equal(t, "report.csv", parsed.files["file"][0].filename)
+	assert.Equal(t, "application/octet-stream", parsed.files["file"][0].contentType)
+	assert.Equal(t, "a,b\n1,2\n", string(parsed.files["file"][0].data))
+
+	_, err = parseForm(newRequest(), formLimits{body: 16}) // nolint:exhaustruct
+
+	assert.ErrorIs(t, err, errBodyTooLarge)
+
+	_, err = parseForm(newRequest(), formLimits{body: 1024, file: 4})
+
+	assert.ErrorIs(t, err, errFileTooLarge)
+
+	// fields are not limited by the file limit
+	parsed, err = parseForm(newRequest(), formLimits{body: 1024, file: 8})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "quarterly report", parsed.fields.Get("title"))
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("a=%zz"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	_, err = parseForm(r, formLimits{}) // nolint:exhaustruct
+
+	assert.Error(t, err)
+
+	r.Header.Set("Content-Type", "application/json")
+
+	assert.False(t, isForm(r))
+}
+
+func Test_router_handle_form(t *testing.T) {
+	t.Parallel()
+
+	runtime := sobek.New()
+	router := newRouter(syncRunner(), nil)
+	router.limits = func() (int64, int64) { return 64, 0 }
+
+	var body, files sobek.Value
+
+	router.handleMethod(runtime, http.MethodPost, "/", func(req *sobek.Object, res *sobek.Object, next sobek.Callable) {
+		body, files = req.Get("body"), req.Get("files")
+	})
+
+	serve := func(data string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(data))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("a=1&b=2&b=3"))
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": []interface{}{"2", "3"}}, body.Export())
+	assert.Equal(t, map[string]interface{}{}, files.Export())
+
+	body, files = nil, nil
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve("a="+strings.Repeat("x", 64)))
+	assert.Equal(t, http.StatusBadRequest, serve("a=%zz"))
+	assert.Nil(t, body)
+	assert.Nil(t, files)
+}
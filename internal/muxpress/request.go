@@ -0,0 +1,221 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/grafana/sobek"
+	"github.com/julienschmidt/httprouter"
+)
+
+// requests holds the Go request of request objects while they are handled, see RequestOf.
+var requests sync.Map // nolint:gochecknoglobals
+
+func wrapRequest(runtime *sobek.Runtime, from *http.Request) *sobek.Object {
+	return wrapFormRequest(runtime, from, nil)
+}
+
+// wrapFormRequest wraps the request with its already parsed form body (nil if the body is not a form).
+func wrapFormRequest(runtime *sobek.Runtime, from *http.Request, parsed *form) *sobek.Object {
+	req := newRequest(runtime, from)
+	req.form = parsed
+	this := runtime.NewObject()
+
+	mustSetGetter(runtime, this, "host", req.host)
+	mustSetGetter(runtime, this, "method", req.method)
+	mustSetGetter(runtime, this, "path", req.path)
+	mustSetGetter(runtime, this, "protocol", req.protocol)
+	mustSetGetter(runtime, this, "params", req.params)
+	mustSetGetter(runtime, this, "query", req.query)
+	mustSetGetter(runtime, this, "cookies", req.cookies)
+	mustSetGetter(runtime, this, "body", req.body)
+	mustSetGetter(runtime, this, "files", req.files)
+
+	mustSet(runtime, this, "get", req.get)
+
+	return this
+}
+
+// RequestOf returns the Go request of a request object passed to handlers and middlewares,
+// or nil if the value is not a request object or the request is already handled.
+// The body of the request can be read again, the router and the request object replace it
+// with a copy after reading.
+func RequestOf(value sobek.Value) *http.Request {
+	obj, isObj := value.(*sobek.Object)
+	if !isObj {
+		return nil
+	}
+
+	if from, found := requests.Load(obj); found {
+		return from.(*http.Request) // nolint:forcetypeassert
+	}
+
+	return nil
+}
+
+func (req *request) get(field string) string {
+	return req.Header.Get(field)
+}
+
+func (req *request) host() string {
+	return req.Host
+}
+
+func (req *request) method() string {
+	return req.Method
+}
+
+func (req *request) path() string {
+	return req.URL.Path
+}
+
+func (req *request) protocol() string {
+	return req.URL.Scheme
+}
+
+type request struct {
+	*http.Request
+	runtime *sobek.Runtime
+
+	paramsOnce sync.Once
+	paramsObj  *sobek.Object
+
+	queryOnce sync.Once
+	queryObj  *sobek.Object
+
+	cookiesOnce sync.Once
+	cookiesObj  *sobek.Object
+
+	bodyOnce  sync.Once
+	bodyValue sobek.Value
+
+	form       *form
+	filesOnce  sync.Once
+	filesValue sobek.Value
+}
+
+func newRequest(runtime *sobek.Runtime, req *http.Request) *request {
+	return &request{Request: req, runtime: runtime} //nolint:exhaustruct
+}
+
+func (req *request) params() *sobek.Object {
+	req.paramsOnce.Do(func() {
+		req.paramsObj = wrapParams(req.runtime, httprouter.ParamsFromContext(req.Context()))
+	})
+
+	return req.paramsObj
+}
+
+func (req *request) query() *sobek.Object {
+	req.queryOnce.Do(func() {
+		req.queryObj = wrapValues(req.runtime, req.URL.Query())
+	})
+
+	return req.queryObj
+}
+
+func (req *request) cookies() *sobek.Object {
+	req.cookiesOnce.Do(func() {
+		req.cookiesObj = wrapCookies(req.runtime, req.Cookies())
+	})
+
+	return req.cookiesObj
+}
+
+func (req *request) body() sobek.Value {
+	req.bodyOnce.Do(func() {
+		if req.form != nil {
+			req.bodyValue = wrapFields(req.runtime, req.form)
+		} else {
+			req.bodyValue = wrapBody(req.runtime, req.Request)
+		}
+	})
+
+	return req.bodyValue
+}
+
+func (req *request) files() sobek.Value {
+	req.filesOnce.Do(func() {
+		if req.form != nil {
+			req.filesValue = wrapFiles(req.runtime, req.form)
+		} else {
+			req.filesValue = sobek.Undefined()
+		}
+	})
+
+	return req.filesValue
+}
+
+func wrapValues(runtime *sobek.Runtime, values url.Values) *sobek.Object {
+	out := runtime.NewObject()
+
+	if len(values) == 0 {
+		return out
+	}
+
+	for key, value := range values {
+		if len(value) == 1 {
+			mustSet(runtime, out, key, value[0])
+		} else {
+			all := []interface{}{}
+
+			for _, str := range value {
+				all = append(all, str)
+			}
+
+			mustSet(runtime, out, key, runtime.NewArray(all...))
+		}
+	}
+
+	return out
+}
+
+func wrapParams(runtime *sobek.Runtime, params httprouter.Params) *sobek.Object {
+	out := runtime.NewObject()
+
+	for _, param := range params {
+		mustSet(runtime, out, param.Key, param.Value)
+	}
+
+	return out
+}
+
+func wrapCookies(runtime *sobek.Runtime, cookies []*http.Cookie) *sobek.Object {
+	out := runtime.NewObject()
+
+	for _, c := range cookies {
+		mustSet(runtime, out, c.Name, c.Value)
+	}
+
+	return out
+}
+
+func wrapBody(runtime *sobek.Runtime, req *http.Request) sobek.Value {
+	if req.ContentLength == 0 || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		return sobek.Undefined()
+	}
+
+	defer req.Body.Close()
+
+	bin, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		throw(runtime, err)
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(bin))
+
+	out := map[string]interface{}{}
+
+	must(runtime, json.Unmarshal(bin, &out))
+
+	return runtime.ToValue(out)
+}
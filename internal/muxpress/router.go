@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/sobek"
+	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/afero"
+)
+
+var errMiddleware = errors.New("middleware failed")
+
+type middleware func(req *sobek.Object, res *sobek.Object, next sobek.Callable)
+
+type middlewareChain []middleware
+
+func (chain middlewareChain) callOne(req *sobek.Object, res *sobek.Object, mware middleware) bool {
+	nextCalled := false
+
+	mware(req, res, func(this sobek.Value, args ...sobek.Value) (sobek.Value, error) {
+		nextCalled = true
+
+		return sobek.Undefined(), nil
+	})
+
+	return nextCalled
+}
+
+func (chain middlewareChain) call(req *sobek.Object, res *sobek.Object, cascade ...middleware) {
+	all := make([]middleware, len(chain)+len(cascade))
+
+	copy(all, chain)
+	copy(all[len(chain):], cascade)
+
+	for _, mware := range all {
+		if !chain.callOne(req, res, mware) {
+			break
+		}
+	}
+}
+
+type router struct {
+	*httprouter.Router
+	runner RunnerFunc
+
+	middlewares middlewareChain
+	filesystem  afero.Fs
+	limits      func() (int64, int64)
+}
+
+func newRouter(runner RunnerFunc, filesystem afero.Fs) *router {
+	return &router{
+		Router:      httprouter.New(),
+		runner:      runner,
+		filesystem:  filesystem,
+		middlewares: make(middlewareChain, 0),
+	}
+}
+
+func (r *router) formLimits() formLimits {
+	if r.limits == nil {
+		return formLimits{}
+	}
+
+	body, file := r.limits()
+
+	return formLimits{body: body, file: file}
+}
+
+func (r *router) use(middlewares ...middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// runSync runs fn by the runner and waits for it, even if fn panics.
+func (r *router) runSync(fn func() error) {
+	done := make(chan struct{}, 1)
+
+	r.runner(func() error {
+		defer func() { done <- struct{}{} }()
+
+		return fn()
+	})
+
+	<-done
+}
+
+// handle calls the middlewares on the request. Form bodies are parsed before, outside of the runner,
+// rejected forms (too large or malformed) are answered without calling the middlewares.
+func (r *router) handle(runtime *sobek.Runtime, response http.ResponseWriter, request *http.Request, middlewares ...middleware) {
+	var parsed *form
+
+	if request.Body != nil && isForm(request) {
+		var err error
+
+		if parsed, err = parseForm(request, r.formLimits()); err != nil {
+			http.Error(response, err.Error(), formStatus(err))
+
+			return
+		}
+	}
+
+	r.runSync(func() (err error) {
+		req := wrapFormRequest(runtime, request, parsed)
+		res := wrapResponseWriter(runtime, response)
+
+		requests.Store(req, request)
+		defer requests.Delete(req)
+
+		// an exception thrown by a middleware is answered with 500 and returned to the runner
+		defer func() {
+			if v := recover(); v != nil {
+				response.WriteHeader(http.StatusInternalServerError)
+
+				if err, _ = v.(error); err == nil {
+					err = fmt.Errorf("%w: %v", errMiddleware, v)
+				}
+			}
+		}()
+
+		r.middlewares.call(req, res, middlewares...)
+
+		return nil
+	})
+}
+
+func (r *router) handleMethod(runtime *sobek.Runtime, method string, path string, middlewares ...middleware) {
+	r.Router.HandlerFunc(method, path, func(response http.ResponseWriter, request *http.Request) {
+		r.handle(runtime, response, request, middlewares...)
+	})
+}
+
+func (r *router) fixpath(path string) string {
+	if strings.HasSuffix(path, "/*filepath") {
+		return path
+	}
+
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	return path + "*filepath"
+}
+
+func (r *router) static(path string, docroot string) {
+	fs := afero.NewHttpFs(afero.NewBasePathFs(r.filesystem, docroot))
+	fileserver := http.FileServer(fs)
+
+	r.Router.HandlerFunc(http.MethodGet, r.fixpath(path), func(response http.ResponseWriter, request *http.Request) {
+		params := httprouter.ParamsFromContext(request.Context())
+
+		request.URL.Path = params.ByName("filepath")
+
+		r.runSync(func() error {
+			fileserver.ServeHTTP(response, request)
+
+			return nil
+		})
+	})
+}
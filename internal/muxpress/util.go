@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package muxpress
+
+import (
+	"fmt"
+
+	"github.com/grafana/sobek"
+)
+
+func throw(runtime *sobek.Runtime, err error) {
+	if e, ok := err.(*sobek.Exception); ok { //nolint:errorlint
+		panic(e)
+	}
+
+	panic(runtime.NewGoError(err))
+}
+
+func throwf(runtime *sobek.Runtime, format string, args ...any) {
+	throw(runtime, fmt.Errorf(format, args...)) //nolint:goerr113
+}
+
+func must(runtime *sobek.Runtime, err error) {
+	if err != nil {
+		throw(runtime, err)
+	}
+}
+
+func mustSet(runtime *sobek.Runtime, obj *sobek.Object, name string, value interface{}) {
+	must(runtime, obj.Set(name, value))
+}
+
+func mustSetGetter(runtime *sobek.Runtime, obj *sobek.Object, name string, getter interface{}) {
+	must(runtime, obj.DefineAccessorProperty(name, runtime.ToValue(getter), sobek.Undefined(), sobek.FLAG_FALSE, sobek.FLAG_TRUE))
+}
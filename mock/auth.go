@@ -0,0 +1,650 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-mock/internal/muxpress"
+)
+
+// Authentication schemes, used as scheme tag of mock_auth_failures metric.
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authAPIKey = "apikey"
+	authHMAC   = "hmac"
+	authSigV4  = "sigv4"
+)
+
+const defaultRealm = "mock"
+
+// authenticator checks the credentials of a request. It returns nil if the request is authenticated.
+// Authenticators are used by the authentication middlewares, they need the raw request
+// (e.g. for verifying signatures).
+type authenticator interface {
+	authenticate(r *http.Request, body []byte) *authFailure
+	needsBody() bool
+}
+
+// authFailure is the response of a request failed to authenticate.
+type authFailure struct {
+	scheme      string
+	status      int
+	challenge   string
+	contentType string
+	body        []byte
+}
+
+func jsonFailure(scheme string, status int, challenge string, code string, message string) *authFailure {
+	body, _ := json.Marshal(map[string]string{"error": code, "message": message}) // nolint:errchkjson
+
+	return &authFailure{
+		scheme:      scheme,
+		status:      status,
+		challenge:   challenge,
+		contentType: "application/json; charset=utf-8",
+		body:        body,
+	}
+}
+
+func equalSecret(given string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(expected)) == 1
+}
+
+// authResult is passed in the request context by the mock server. Authentication middlewares record
+// the scheme of the failure in it, for the mock_auth_failures metric.
+type authResult struct {
+	scheme string
+}
+
+type authResultKey struct{}
+
+// withAuthResult returns a shallow copy of the request with an empty authentication result in its context.
+func withAuthResult(r *http.Request) (*http.Request, *authResult) {
+	result := new(authResult)
+
+	return r.WithContext(context.WithValue(r.Context(), authResultKey{}, result)), result
+}
+
+// authenticate checks the request by the authenticator. The request body is read only if the authenticator
+// needs it, the body of the request is replaced by a copy.
+func authenticate(auth authenticator, r *http.Request) *authFailure {
+	var body []byte
+
+	if auth.needsBody() && r.Body != nil {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return &authFailure{ // nolint:exhaustruct
+				status:      http.StatusBadRequest,
+				contentType: "text/plain; charset=utf-8",
+				body:        []byte(err.Error()),
+			}
+		}
+
+		r.Body.Close() // nolint:errcheck
+
+		body = data
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return auth.authenticate(r, body)
+}
+
+// basicAuth checks HTTP Basic credentials.
+type basicAuth struct {
+	users map[string]string
+	realm string
+}
+
+func (auth *basicAuth) needsBody() bool { return false }
+
+func (auth *basicAuth) authenticate(r *http.Request, _ []byte) *authFailure {
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", auth.realm)
+
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return jsonFailure(authBasic, http.StatusUnauthorized, challenge, "unauthorized", "missing credentials")
+	}
+
+	expected, found := auth.users[user]
+	if !found || !equalSecret(password, expected) {
+		return jsonFailure(authBasic, http.StatusUnauthorized, challenge, "unauthorized", "invalid credentials")
+	}
+
+	return nil
+}
+
+// bearerAuth checks bearer tokens (RFC 6750) and their scopes.
+type bearerAuth struct {
+	tokens map[string][]string
+	scopes []string
+	realm  string
+}
+
+func (auth *bearerAuth) needsBody() bool { return false }
+
+func (auth *bearerAuth) authenticate(r *http.Request, _ []byte) *authFailure {
+	challenge := fmt.Sprintf("Bearer realm=%q", auth.realm)
+
+	token, found := bearerToken(r)
+	if !found {
+		return jsonFailure(authBearer, http.StatusUnauthorized, challenge, "unauthorized", "missing bearer token")
+	}
+
+	granted, found := auth.tokens[token]
+	if !found {
+		return jsonFailure(authBearer, http.StatusUnauthorized,
+			challenge+`, error="invalid_token", error_description="The access token is invalid"`,
+			"invalid_token", "invalid bearer token")
+	}
+
+	for _, scope := range auth.scopes {
+		if !contains(granted, scope) {
+			return jsonFailure(authBearer, http.StatusForbidden,
+				challenge+fmt.Sprintf(`, error="insufficient_scope", scope=%q`, strings.Join(auth.scopes, " ")),
+				"insufficient_scope", "missing scope: "+scope)
+		}
+	}
+
+	return nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	return parseBearer(r.Header.Get("Authorization"))
+}
+
+// parseBearer returns the token of a Bearer authorization header value.
+func parseBearer(header string) (string, bool) {
+	const prefix = "bearer "
+
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+func contains(list []string, item string) bool {
+	for _, value := range list {
+		if value == item {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apiKeyAuth checks API keys in a request header or in a query parameter.
+type apiKeyAuth struct {
+	keys   []string
+	header string
+	query  string
+	realm  string
+}
+
+func (auth *apiKeyAuth) needsBody() bool { return false }
+
+func (auth *apiKeyAuth) authenticate(r *http.Request, _ []byte) *authFailure {
+	var key, challenge string
+
+	if len(auth.query) != 0 {
+		key = r.URL.Query().Get(auth.query)
+		challenge = fmt.Sprintf("ApiKey realm=%q, query=%q", auth.realm, auth.query)
+	} else {
+		key = r.Header.Get(auth.header)
+		challenge = fmt.Sprintf("ApiKey realm=%q, header=%q", auth.realm, auth.header)
+	}
+
+	if len(key) == 0 {
+		return jsonFailure(authAPIKey, http.StatusUnauthorized, challenge, "unauthorized", "missing API key")
+	}
+
+	for _, expected := range auth.keys {
+		if equalSecret(key, expected) {
+			return nil
+		}
+	}
+
+	return jsonFailure(authAPIKey, http.StatusForbidden, "", "forbidden", "invalid API key")
+}
+
+// hmacAuth checks the HMAC signature of the request body, given in a request header
+// (e.g. X-Hub-Signature-256: sha256=...).
+type hmacAuth struct {
+	secret    []byte
+	header    string
+	prefix    string
+	algorithm func() hash.Hash
+	encoding  string
+}
+
+func (auth *hmacAuth) needsBody() bool { return true }
+
+func (auth *hmacAuth) authenticate(r *http.Request, body []byte) *authFailure {
+	given := r.Header.Get(auth.header)
+	if len(given) == 0 {
+		return jsonFailure(authHMAC, http.StatusUnauthorized, fmt.Sprintf("HMAC header=%q", auth.header),
+			"unauthorized", "missing signature")
+	}
+
+	mac := hmac.New(auth.algorithm, auth.secret)
+	mac.Write(body) // nolint:errcheck
+
+	var expected string
+
+	if auth.encoding == "base64" {
+		expected = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	} else {
+		expected = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	if !equalSecret(strings.TrimPrefix(given, auth.prefix), expected) || !strings.HasPrefix(given, auth.prefix) {
+		return jsonFailure(authHMAC, http.StatusForbidden, "", "forbidden", "signature does not match")
+	}
+
+	return nil
+}
+
+// sigv4Auth checks AWS Signature Version 4 signed requests (Authorization header).
+type sigv4Auth struct {
+	keys    map[string]string
+	region  string
+	service string
+	now     func() time.Time
+}
+
+const (
+	sigv4Algorithm = "AWS4-HMAC-SHA256"
+	sigv4TimeFmt   = "20060102T150405Z"
+	sigv4MaxSkew   = 15 * time.Minute
+
+	sigv4UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+func (auth *sigv4Auth) needsBody() bool { return true }
+
+func sigv4Failure(status int, code string, message string) *authFailure {
+	body := fmt.Sprintf(
+		"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Error><Code>%s</Code><Message>%s</Message></Error>",
+		code, message,
+	)
+
+	return &authFailure{
+		scheme:      authSigV4,
+		status:      status,
+		contentType: "application/xml",
+		body:        []byte(body),
+	}
+}
+
+func (auth *sigv4Auth) authenticate(r *http.Request, body []byte) *authFailure { // nolint:cyclop
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, sigv4Algorithm+" ") {
+		return sigv4Failure(http.StatusForbidden, "MissingAuthenticationToken", "Missing Authentication Token")
+	}
+
+	fields := make(map[string]string)
+
+	for _, field := range strings.Split(strings.TrimPrefix(header, sigv4Algorithm+" "), ",") {
+		if key, value, found := strings.Cut(strings.TrimSpace(field), "="); found {
+			fields[key] = value
+		}
+	}
+
+	credential := strings.Split(fields["Credential"], "/")
+	if len(credential) != 5 || credential[4] != "aws4_request" || //nolint:gomnd
+		len(fields["SignedHeaders"]) == 0 || len(fields["Signature"]) == 0 {
+		return sigv4Failure(http.StatusForbidden, "IncompleteSignature", "Authorization header is malformed")
+	}
+
+	secret, found := auth.keys[credential[0]]
+	if !found {
+		return sigv4Failure(http.StatusForbidden, "InvalidAccessKeyId",
+			"The AWS access key Id you provided does not exist in our records.")
+	}
+
+	if (len(auth.region) != 0 && credential[2] != auth.region) || (len(auth.service) != 0 && credential[3] != auth.service) {
+		return sigv4Failure(http.StatusForbidden, "SignatureDoesNotMatch", "Credential should be scoped to a valid region or service")
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+
+	date, err := time.Parse(sigv4TimeFmt, amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, credential[1]) {
+		return sigv4Failure(http.StatusForbidden, "IncompleteSignature", "X-Amz-Date header is missing or invalid")
+	}
+
+	if skew := auth.now().Sub(date); skew > sigv4MaxSkew || skew < -sigv4MaxSkew {
+		return sigv4Failure(http.StatusForbidden, "RequestTimeTooSkewed",
+			"The difference between the request time and the current time is too large.")
+	}
+
+	// the canonical request uses the payload hash sent by the client, so it must match the body
+	if payload := r.Header.Get("X-Amz-Content-Sha256"); len(payload) != 0 && payload != sigv4UnsignedPayload {
+		if digest := sha256.Sum256(body); !strings.EqualFold(payload, hex.EncodeToString(digest[:])) {
+			return sigv4Failure(http.StatusBadRequest, "XAmzContentSHA256Mismatch",
+				"The provided 'x-amz-content-sha256' header does not match what was computed.")
+		}
+	}
+
+	scope := strings.Join(credential[1:], "/")
+	canonical := sigv4CanonicalRequest(r, strings.Split(fields["SignedHeaders"], ";"), body)
+	digest := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{sigv4Algorithm, amzDate, scope, hex.EncodeToString(digest[:])}, "\n")
+
+	key := []byte("AWS4" + secret)
+
+	for _, part := range credential[1:] {
+		key = hmacSHA256(key, part)
+	}
+
+	if !equalSecret(fields["Signature"], hex.EncodeToString(hmacSHA256(key, toSign))) {
+		return sigv4Failure(http.StatusForbidden, "SignatureDoesNotMatch",
+			"The request signature we calculated does not match the signature you provided.")
+	}
+
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data)) // nolint:errcheck
+
+	return mac.Sum(nil)
+}
+
+// sigv4CanonicalRequest returns the canonical form of the request, as defined by AWS Signature Version 4.
+func sigv4CanonicalRequest(r *http.Request, signed []string, body []byte) string {
+	path := r.URL.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+
+	for key := range query {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+
+		sort.Strings(values)
+
+		for _, value := range values {
+			params = append(params, sigv4Escape(key)+"="+sigv4Escape(value))
+		}
+	}
+
+	var headers strings.Builder
+
+	for _, name := range signed {
+		value := r.Header.Get(name)
+
+		// clients sign the host of the mock target, the mock server restores it for rewritten requests
+		if strings.EqualFold(name, "host") {
+			value = r.Host
+		}
+
+		headers.WriteString(strings.ToLower(name) + ":" + strings.Join(strings.Fields(value), " ") + "\n")
+	}
+
+	payload := r.Header.Get("X-Amz-Content-Sha256")
+	if len(payload) == 0 {
+		digest := sha256.Sum256(body)
+		payload = hex.EncodeToString(digest[:])
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		path,
+		strings.Join(params, "&"),
+		headers.String(),
+		strings.Join(signed, ";"),
+		payload,
+	}, "\n")
+}
+
+func sigv4Escape(str string) string {
+	return strings.ReplaceAll(url.QueryEscape(str), "+", "%20")
+}
+
+// authObject returns the mock.auth namespace, containing the authentication middleware factories.
+func (mod *Module) authObject() *sobek.Object {
+	obj := mod.runtime().NewObject()
+
+	factory := func(parse func(opts *sobek.Object) authenticator) func(sobek.Value) sobek.Value {
+		return func(value sobek.Value) sobek.Value {
+			opts, isObj := value.(*sobek.Object)
+			if !isObj {
+				mod.throwf("missing authentication options", errInvalidArg)
+			}
+
+			return mod.authMiddleware(parse(opts))
+		}
+	}
+
+	obj.Set("basic", factory(mod.newBasicAuth))   // nolint:errcheck
+	obj.Set("bearer", factory(mod.newBearerAuth)) // nolint:errcheck
+	obj.Set("apiKey", factory(mod.newAPIKeyAuth)) // nolint:errcheck
+	obj.Set("hmac", factory(mod.newHMACAuth))     // nolint:errcheck
+	obj.Set("sigv4", factory(mod.newSigV4Auth))   // nolint:errcheck
+
+	return obj
+}
+
+// authMiddleware returns a middleware checking the credentials of requests by the authenticator.
+// Requests failed to authenticate are answered by the middleware, the next middleware is not called.
+// Preflight requests never carry credentials, they are passed to the next middleware.
+func (mod *Module) authMiddleware(auth authenticator) *sobek.Object {
+	return mod.runtime().ToValue(func(call sobek.FunctionCall) sobek.Value {
+		r := muxpress.RequestOf(call.Argument(0))
+		if r == nil {
+			mod.throwf("authentication middleware must be used by an application", errInvalidArg)
+		}
+
+		if !isPreflight(r) {
+			if failure := authenticate(auth, r); failure != nil {
+				if result, found := r.Context().Value(authResultKey{}).(*authResult); found {
+					result.scheme = failure.scheme
+				}
+
+				mod.writeFailure(call.Argument(1).ToObject(mod.runtime()), failure)
+
+				return sobek.Undefined()
+			}
+		}
+
+		if next, ok := sobek.AssertFunction(call.Argument(2)); ok {
+			if _, err := next(sobek.Undefined()); err != nil {
+				mod.throw(err)
+			}
+		}
+
+		return sobek.Undefined()
+	}).(*sobek.Object) // nolint:forcetypeassert
+}
+
+// writeFailure answers the request by the failure, using the response object of the application.
+func (mod *Module) writeFailure(res *sobek.Object, failure *authFailure) {
+	if len(failure.challenge) != 0 {
+		mod.invoke(res, "set", "WWW-Authenticate", failure.challenge)
+	}
+
+	mod.invoke(res, "set", "Content-Type", failure.contentType)
+
+	// status sends the headers, so the content type set by text is not used
+	mod.invoke(res, "status", failure.status)
+	mod.invoke(res, "text", "%s", string(failure.body))
+}
+
+func (mod *Module) optString(opts *sobek.Object, name string, def string) string {
+	if v := opts.Get(name); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+		return v.String()
+	}
+
+	return def
+}
+
+func (mod *Module) optStrings(opts *sobek.Object, name string) []string {
+	v := opts.Get(name)
+	if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return nil
+	}
+
+	if v.ExportType().Kind() == reflect.String {
+		return strings.Fields(strings.ReplaceAll(v.String(), ",", " "))
+	}
+
+	var list []string
+
+	if err := mod.runtime().ExportTo(v, &list); err != nil {
+		mod.throwf("%s must be string or array of strings", errInvalidArg, name)
+	}
+
+	return list
+}
+
+func (mod *Module) optMap(opts *sobek.Object, name string) map[string]string {
+	v := opts.Get(name)
+	if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+		mod.throwf("missing %s", errInvalidArg, name)
+	}
+
+	var values map[string]string
+
+	if err := mod.runtime().ExportTo(v, &values); err != nil {
+		mod.throwf("%s must be an object with string values", errInvalidArg, name)
+	}
+
+	return values
+}
+
+func (mod *Module) newBasicAuth(opts *sobek.Object) authenticator {
+	return &basicAuth{users: mod.optMap(opts, "users"), realm: mod.optString(opts, "realm", defaultRealm)}
+}
+
+func (mod *Module) newBearerAuth(opts *sobek.Object) authenticator {
+	auth := &bearerAuth{
+		tokens: make(map[string][]string),
+		scopes: mod.optStrings(opts, "scope"),
+		realm:  mod.optString(opts, "realm", defaultRealm),
+	}
+
+	tokens := opts.Get("tokens")
+	if tokens == nil || sobek.IsUndefined(tokens) || sobek.IsNull(tokens) {
+		mod.throwf("missing tokens", errInvalidArg)
+	}
+
+	if list, isArray := tokens.Export().([]interface{}); isArray {
+		for _, token := range list {
+			auth.tokens[fmt.Sprint(token)] = nil
+		}
+
+		return auth
+	}
+
+	obj := tokens.ToObject(mod.runtime())
+
+	for _, token := range obj.Keys() {
+		auth.tokens[token] = mod.optStrings(obj, token)
+	}
+
+	return auth
+}
+
+func (mod *Module) newAPIKeyAuth(opts *sobek.Object) authenticator {
+	auth := &apiKeyAuth{
+		keys:   mod.optStrings(opts, "keys"),
+		header: mod.optString(opts, "header", "X-API-Key"),
+		query:  mod.optString(opts, "query", ""),
+		realm:  mod.optString(opts, "realm", defaultRealm),
+	}
+
+	if len(auth.keys) == 0 {
+		mod.throwf("missing keys", errInvalidArg)
+	}
+
+	return auth
+}
+
+func (mod *Module) newHMACAuth(opts *sobek.Object) authenticator {
+	auth := &hmacAuth{
+		secret:   []byte(mod.optString(opts, "secret", "")),
+		header:   mod.optString(opts, "header", "X-Signature"),
+		prefix:   mod.optString(opts, "prefix", ""),
+		encoding: mod.optString(opts, "encoding", "hex"),
+	}
+
+	if len(auth.secret) == 0 {
+		mod.throwf("missing secret", errInvalidArg)
+	}
+
+	switch algorithm := mod.optString(opts, "algorithm", "sha256"); algorithm {
+	case "sha1":
+		auth.algorithm = sha1.New
+	case "sha256":
+		auth.algorithm = sha256.New
+	case "sha512":
+		auth.algorithm = sha512.New
+	default:
+		mod.throwf("unsupported HMAC algorithm: %s", errInvalidArg, algorithm)
+	}
+
+	if auth.encoding != "hex" && auth.encoding != "base64" {
+		mod.throwf("HMAC encoding must be hex or base64: %s", errInvalidArg, auth.encoding)
+	}
+
+	return auth
+}
+
+func (mod *Module) newSigV4Auth(opts *sobek.Object) authenticator {
+	auth := &sigv4Auth{
+		region:  mod.optString(opts, "region", ""),
+		service: mod.optString(opts, "service", ""),
+		now:     time.Now,
+	}
+
+	if v := opts.Get("keys"); v != nil && !sobek.IsUndefined(v) {
+		auth.keys = mod.optMap(opts, "keys")
+	} else {
+		auth.keys = map[string]string{
+			mod.optString(opts, "accessKeyId", ""): mod.optString(opts, "secretAccessKey", ""),
+		}
+	}
+
+	for id, secret := range auth.keys {
+		if len(id) == 0 || len(secret) == 0 {
+			mod.throwf("missing access key id or secret access key", errInvalidArg)
+		}
+	}
+
+	return auth
+}
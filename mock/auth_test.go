@@ -0,0 +1,380 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	auth := &basicAuth{users: map[string]string{"alice": "secret"}, realm: defaultRealm}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	failure := auth.authenticate(r, nil)
+
+	assert.Equal(t, http.StatusUnauthorized, failure.status)
+	assert.Equal(t, `Basic realm="mock", charset="UTF-8"`, failure.challenge)
+
+	r.SetBasicAuth("alice", "wrong")
+
+	assert.Equal(t, http.StatusUnauthorized, auth.authenticate(r, nil).status)
+
+	r.SetBasicAuth("alice", "secret")
+
+	assert.Nil(t, auth.authenticate(r, nil))
+}
+
+func TestBearerAuth(t *testing.T) {
+	t.Parallel()
+
+	auth := &bearerAuth{
+		tokens: map[string][]string{"t1": {"read", "write"}, "t2": {"read"}},
+		scopes: []string{"write"},
+		realm:  defaultRealm,
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	assert.Equal(t, `Bearer realm="mock"`, auth.authenticate(r, nil).challenge)
+
+	r.Header.Set("Authorization", "Bearer t3")
+
+	failure := auth.authenticate(r, nil)
+
+	assert.Equal(t, http.StatusUnauthorized, failure.status)
+	assert.Contains(t, failure.challenge, `error="invalid_token"`)
+
+	r.Header.Set("Authorization", "Bearer t2")
+
+	failure = auth.authenticate(r, nil)
+
+	assert.Equal(t, http.StatusForbidden, failure.status)
+	assert.Contains(t, failure.challenge, `error="insufficient_scope", scope="write"`)
+
+	r.Header.Set("Authorization", "bearer t1")
+
+	assert.Nil(t, auth.authenticate(r, nil))
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	t.Parallel()
+
+	auth := &apiKeyAuth{keys: []string{"k1"}, header: "X-API-Key", realm: defaultRealm}
+	r := httptest.NewRequest(http.MethodGet, "/?api_key=k1", nil)
+
+	assert.Equal(t, http.StatusUnauthorized, auth.authenticate(r, nil).status)
+
+	r.Header.Set("X-API-Key", "k2")
+
+	assert.Equal(t, http.StatusForbidden, auth.authenticate(r, nil).status)
+
+	r.Header.Set("X-API-Key", "k1")
+
+	assert.Nil(t, auth.authenticate(r, nil))
+
+	auth.query = "api_key"
+
+	assert.Nil(t, auth.authenticate(r, nil))
+	assert.Equal(t, http.StatusUnauthorized, auth.authenticate(httptest.NewRequest(http.MethodGet, "/", nil), nil).status)
+}
+
+func TestHMACAuth(t *testing.T) {
+	t.Parallel()
+
+	auth := &hmacAuth{
+		secret:    []byte("secret"),
+		header:    "X-Hub-Signature-256",
+		prefix:    "sha256=",
+		algorithm: sha256.New,
+		encoding:  "hex",
+	}
+
+	body := []byte(`{"event":"push"}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body) // nolint:errcheck
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	assert.Equal(t, http.StatusUnauthorized, auth.authenticate(r, body).status)
+
+	r.Header.Set("X-Hub-Signature-256", hex.EncodeToString(mac.Sum(nil)))
+
+	assert.Equal(t, http.StatusForbidden, auth.authenticate(r, body).status)
+
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	assert.Nil(t, auth.authenticate(r, body))
+	assert.Equal(t, http.StatusForbidden, auth.authenticate(r, []byte("{}")).status)
+}
+
+func TestSigV4Auth(t *testing.T) {
+	t.Parallel()
+
+	// example request of AWS Signature Version 4 documentation
+	auth := &sigv4Auth{
+		keys:    map[string]string{"AKIDEXAMPLE": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+		region:  "us-east-1",
+		service: "iam",
+		now:     func() time.Time { return time.Date(2015, 8, 30, 12, 40, 0, 0, time.UTC) },
+	}
+
+	newRequest := func(signature string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+		r.Header.Set("X-Amz-Date", "20150830T123600Z")
+		r.Header.Set("Authorization", "AWS4-HMAC-SHA256 "+
+			"Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-date, "+
+			"Signature="+signature)
+
+		return r
+	}
+
+	valid := "5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+
+	assert.Nil(t, auth.authenticate(newRequest(valid), nil))
+
+	failure := auth.authenticate(newRequest(strings.Repeat("0", len(valid))), nil)
+
+	assert.Equal(t, http.StatusForbidden, failure.status)
+	assert.Contains(t, string(failure.body), "<Code>SignatureDoesNotMatch</Code>")
+
+	r := newRequest(valid)
+
+	r.Header.Set("Authorization", strings.Replace(r.Header.Get("Authorization"), "AKIDEXAMPLE", "AKIDOTHER", 1))
+
+	assert.Contains(t, string(auth.authenticate(r, nil).body), "<Code>InvalidAccessKeyId</Code>")
+
+	r = newRequest(valid)
+
+	r.Header.Del("Authorization")
+
+	assert.Contains(t, string(auth.authenticate(r, nil).body), "<Code>MissingAuthenticationToken</Code>")
+
+	// the payload hash sent by the client must match the body
+	empty := sha256.Sum256(nil)
+
+	r = newRequest(valid)
+
+	r.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(empty[:]))
+
+	assert.Nil(t, auth.authenticate(r, nil))
+
+	failure = auth.authenticate(r, []byte("tampered"))
+
+	assert.Equal(t, http.StatusBadRequest, failure.status)
+	assert.Contains(t, string(failure.body), "<Code>XAmzContentSHA256Mismatch</Code>")
+
+	// unsigned payloads are not checked, the signature covers the UNSIGNED-PAYLOAD value
+	r.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	assert.Contains(t, string(auth.authenticate(r, []byte("tampered")).body), "<Code>SignatureDoesNotMatch</Code>")
+
+	auth.now = time.Now
+
+	assert.Contains(t, string(auth.authenticate(newRequest(valid), nil).body), "<Code>RequestTimeTooSkewed</Code>")
+}
+
+func TestModuleSigV4Auth(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://s3.example.com", app => {
+  app.use(mock.auth.sigv4({ accessKeyId: "AKID", secretAccessKey: "SECRET", region: "us-east-1", service: "s3" }))
+  app.get("/bucket", (req, res) => res.text(req.host))
+}, { sync: true })
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	// sign returns the headers of a request to /bucket signed for given host, the way an AWS client signs it
+	sign := func(host string) map[string]string {
+		now := time.Now().UTC()
+		date := now.Format(sigv4TimeFmt)
+		scope := date[:8] + "/us-east-1/s3/aws4_request"
+
+		r := httptest.NewRequest(http.MethodGet, "https://"+host+"/bucket", nil)
+		r.Header.Set("X-Amz-Date", date)
+
+		digest := sha256.Sum256([]byte(sigv4CanonicalRequest(r, []string{"host", "x-amz-date"}, nil)))
+		key := []byte("AWS4SECRET")
+
+		for _, part := range strings.Split(scope, "/") {
+			key = hmacSHA256(key, part)
+		}
+
+		signature := hex.EncodeToString(hmacSHA256(key, strings.Join([]string{sigv4Algorithm, date, scope, hex.EncodeToString(digest[:])}, "\n")))
+
+		return map[string]string{
+			"X-Amz-Date":    date,
+			"Authorization": sigv4Algorithm + " Credential=AKID/" + scope + ", SignedHeaders=host;x-amz-date, Signature=" + signature,
+		}
+	}
+
+	local := strings.TrimPrefix(helper.module.lookup["https://s3.example.com"], "http://")
+
+	assert.NoError(t, helper.vu.Runtime().Set("target", sign("s3.example.com")))
+	assert.NoError(t, helper.vu.Runtime().Set("local", sign(local)))
+
+	value, err := helper.vu.Runtime().RunString(`
+;[
+  http.get("https://s3.example.com/bucket", { headers: target }),
+  http.get("https://s3.example.com/bucket", { headers: local }),
+].map(res => [res.status, res.status == 200 ? res.body : ""])
+`)
+
+	assert.NoError(t, err)
+
+	// the signature covers the host of the target, not the address the mock server is listening on
+	assert.Equal(t, []interface{}{
+		[]interface{}{int64(200), "s3.example.com"},
+		[]interface{}{int64(403), ""},
+	}, value.Export())
+}
+
+func TestModuleAuth(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	samples := moveToVUContext(helper)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://auth.example.com", app => {
+  app.use(mock.auth.apiKey({ keys: ["k1"] }))
+  app.get("/public", (req, res) => res.text("public"))
+  app.get("/admin", mock.auth.bearer({ tokens: { t1: "admin" }, scope: "admin" }), (req, res) => res.text("admin"))
+  app.post("/hook", mock.auth.hmac({ secret: "secret", header: "X-Signature" }), (req, res) => res.text("hook"))
+}, { sync: true })
+`)
+
+	assert.NoError(t, err)
+
+	base := helper.module.lookup["https://auth.example.com"]
+
+	resp, err := req.Get(base + "/public")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `ApiKey realm="mock", header="X-API-Key"`, resp.Header.Get("WWW-Authenticate"))
+
+	resp, err = req.R().SetHeader("X-API-Key", "k1").Get(base + "/public")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "public", resp.String())
+
+	resp, err = req.R().SetHeader("X-API-Key", "k1").Get(base + "/admin")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = req.R().SetHeader("X-API-Key", "k1").SetBearerAuthToken("t1").Get(base + "/admin")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", resp.String())
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("payload")) // nolint:errcheck
+
+	resp, err = req.R().SetHeader("X-API-Key", "k1").SetHeader("X-Signature", hex.EncodeToString(mac.Sum(nil))).
+		SetBodyString("payload").Post(base + "/hook")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hook", resp.String())
+
+	failures := map[string]int{}
+
+	for len(samples) != 0 {
+		for _, sample := range (<-samples).GetSamples() {
+			if sample.Metric == helper.module.metrics.authFailures {
+				failures[sample.Tags.Map()["scheme"]+" "+sample.Tags.Map()["status"]]++
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]int{"apikey 401": 1, "bearer 401": 1}, failures)
+
+	_, err = helper.vu.Runtime().RunString(`mock.auth.hmac({ secret: "s", algorithm: "md5" })`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	_, err = helper.vu.Runtime().RunString(`mock.auth.basic()`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
+
+func TestModuleAuthMiddleware(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://auth.example.com", app => {
+  app.get("/early", (req, res) => res.text("early"), mock.auth.bearer({ tokens: { t1: "user" } }))
+  app.post("/form", mock.auth.hmac({ secret: "secret", header: "X-Signature" }), (req, res) => res.text(req.body.name))
+}, { sync: true })
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	base := helper.module.lookup["https://auth.example.com"]
+
+	// the middlewares are called in order, the handler answers before the authentication middleware
+	resp, err := req.Get(base + "/early")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "early", resp.String())
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("name=gopher")) // nolint:errcheck
+
+	// the form body read by the router is read again by the authentication middleware
+	resp, err = req.R().SetHeader("X-Signature", hex.EncodeToString(mac.Sum(nil))).
+		SetFormData(map[string]string{"name": "gopher"}).Post(base + "/form")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "gopher", resp.String())
+
+	value, err := helper.vu.Runtime().RunString(`
+const standalone = new Application({ sync: true })
+
+standalone.use(mock.auth.basic({ users: { user: "pass" } }))
+standalone.get("/items", (req, res) => res.json([]))
+standalone.listen()
+
+const res = [
+  http.get("http://" + standalone.host + "/items"),
+  http.get("http://user:pass@" + standalone.host + "/items"),
+]
+
+standalone.shutdown()
+
+res.map(r => r.status)
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(401), int64(200)}, value.Export())
+
+	_, err = helper.vu.Runtime().RunString(`mock.auth.basic({ users: {} })({}, {}, () => {})`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+// intercept registers the targets of the mock definitions as request interception rules of a browser page
+// (or browser context), using its route method. Requests of the page to mocked targets are fulfilled
+// by the mock servers, other requests are continued. Mock definitions created later are not registered.
+// Browser versions without route method are rejected, requests of their pages can not be intercepted.
+func (mod *Module) intercept(page sobek.Value) {
+	obj, isObj := page.(*sobek.Object)
+	if !isObj {
+		mod.throwf("page must be an object", errInvalidArg)
+	}
+
+	route, isFunc := sobek.AssertFunction(obj.Get("route"))
+	if !isFunc {
+		mod.throwf("page must have route method, request interception is not supported by this browser version",
+			errInvalidArg)
+	}
+
+	handler := mod.runtime().ToValue(mod.fulfill)
+
+	for _, target := range mod.targets() {
+		pattern, err := mod.runtime().New(mod.runtime().Get("RegExp"), mod.runtime().ToValue("^"+regexp.QuoteMeta(target)))
+		if err != nil {
+			mod.throw(err)
+		}
+
+		if _, err := route(obj, pattern, handler); err != nil {
+			mod.throw(err)
+		}
+	}
+}
+
+// fulfill is the route handler of intercepted browser requests. It returns the result (a promise)
+// of the route's fulfill or continue method, so the browser waits for it like for async handlers.
+func (mod *Module) fulfill(route *sobek.Object) sobek.Value {
+	call := func(obj *sobek.Object, method string, args ...sobek.Value) sobek.Value {
+		fn, isFunc := sobek.AssertFunction(obj.Get(method))
+		if !isFunc {
+			mod.throwf("route handler: missing %s method", errInvalidArg, method)
+		}
+
+		v, err := fn(obj, args...)
+		if err != nil {
+			mod.throw(err)
+		}
+
+		return v
+	}
+
+	request := call(route, "request").ToObject(mod.runtime())
+	loc := call(request, "url").String()
+	method := call(request, "method").String()
+
+	args := []sobek.Value{mod.runtime().ToValue(loc)}
+
+	target := mod.rewrite(args, 0)
+	if len(target) == 0 {
+		return call(route, "continue")
+	}
+
+	mod.checkSyncCall("route", target)
+
+	var body io.Reader
+
+	if data := call(request, "postData"); data != nil && !sobek.IsNull(data) && !sobek.IsUndefined(data) {
+		body = strings.NewReader(data.String())
+	}
+
+	req, err := http.NewRequest(method, args[0].String(), body) // nolint:noctx
+	if err != nil {
+		mod.throw(err)
+	}
+
+	if headers, isObj := call(request, "headers").(*sobek.Object); isObj {
+		for _, key := range headers.Keys() {
+			if !strings.EqualFold(key, "host") && !strings.EqualFold(key, "content-length") {
+				req.Header.Set(key, headers.Get(key).String())
+			}
+		}
+	}
+
+	req.Host = req.URL.Host
+
+	mod.calls.enter()
+
+	resp, data, err := mod.roundTrip(req)
+
+	mod.calls.exit()
+
+	if err != nil {
+		mod.throw(err)
+	}
+
+	headers := mod.runtime().NewObject()
+
+	for key, values := range resp.Header {
+		headers.Set(strings.ToLower(key), joinHeader(key, values)) // nolint:errcheck
+	}
+
+	response := mod.runtime().NewObject()
+
+	response.Set("status", resp.StatusCode)                      // nolint:errcheck
+	response.Set("headers", headers)                             // nolint:errcheck
+	response.Set("body", mod.runtime().NewArrayBuffer(data))     // nolint:errcheck
+	response.Set("contentType", resp.Header.Get("Content-Type")) // nolint:errcheck
+
+	return call(route, "fulfill", response)
+}
+
+// joinHeader returns the values of a header as a single value, the way browsers expect them in fulfilled
+// responses: Set-Cookie values are separated by newlines, other values by commas.
+func joinHeader(key string, values []string) string {
+	if strings.EqualFold(key, "Set-Cookie") {
+		return strings.Join(values, "\n")
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// roundTrip sends the request to the mock server and reads the response body.
+func (mod *Module) roundTrip(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := mod.browserClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close() // nolint:errcheck
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, data, nil
+}
+
+// browserClient returns the client of intercepted browser requests, created on first use and shared by
+// all requests of the VU. Redirects are not followed, the browser follows them.
+func (mod *Module) browserClient() *http.Client {
+	if mod.browser != nil {
+		return mod.browser
+	}
+
+	base := http.DefaultTransport.(*http.Transport).Clone() // nolint:forcetypeassert
+	base.Proxy = nil
+
+	mod.browser = &http.Client{ // nolint:exhaustruct
+		Transport: &mockTransport{base: base, mocks: mod.dialers},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return mod.browser
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pageScript defines a stand-in for the routing layer of a browser page.
+const pageScript = `
+const routes = []
+
+const page = {
+  route(pattern, handler) {
+    routes.push({ pattern, handler })
+  }
+}
+
+function visit(url, method, postData) {
+  const route = routes.find(route => route.pattern.test(url))
+  if (!route) {
+    return "unrouted"
+  }
+
+  let result
+
+  const handled = route.handler({
+    request: () => ({
+      url: () => url,
+      method: () => method || "GET",
+      headers: () => Object.assign({ "accept": "text/plain", "host": "example.com" },
+        postData ? { "content-type": "application/json" } : {}),
+      postData: () => postData || null,
+    }),
+    fulfill: response => {
+      result = {
+        status: response.status,
+        type: response.headers["content-type"],
+        body: String.fromCharCode(...new Uint8Array(response.body)),
+      }
+
+      if (response.headers["set-cookie"]) {
+        result.cookies = response.headers["set-cookie"]
+      }
+
+      return Promise.resolve()
+    },
+    continue: () => {
+      result = "continue"
+
+      return Promise.resolve()
+    },
+  })
+
+  // the handler returns the promise of fulfill or continue, like async handlers
+  if (!(handled instanceof Promise)) {
+    throw new Error("route handler must return a promise")
+  }
+
+  return result
+}
+`
+
+func TestModuleIntercept(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(pageScript + `
+mock("https://example.com", app => {
+  app.get("/hello", (req, res) => res.text("Hello " + req.get("accept")))
+  app.get("/login", (req, res) => {
+    res.append("Set-Cookie", "a=1")
+    res.append("Set-Cookie", "b=2")
+    res.text("ok")
+  })
+  app.post("/echo", (req, res) => res.json(req.body))
+}, { name: "api" })
+
+mock("https://cdn.example.net", app => {
+  app.get("/lib.js", (req, res) => res.text("lib"))
+}, { memory: true })
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+mock.intercept(page)
+
+const result = [
+  visit("https://example.com/hello"),
+  visit("https://example.com/login"),
+  visit("https://example.com/echo", "POST", '{"foo":"bar"}'),
+  visit("https://cdn.example.net/lib.js"),
+  visit("https://example.org/"),
+]
+
+mock.disable("api")
+
+result.push(visit("https://example.com/hello"))
+
+result
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"status": int64(200), "type": "text/plain; charset=utf-8", "body": "Hello text/plain"},
+		map[string]interface{}{"status": int64(200), "type": "text/plain; charset=utf-8", "body": "ok", "cookies": "a=1\nb=2"},
+		map[string]interface{}{"status": int64(200), "type": "application/json; charset=utf-8", "body": `{"foo":"bar"}`},
+		map[string]interface{}{"status": int64(200), "type": "text/plain; charset=utf-8", "body": "lib"},
+		"unrouted",
+		"continue",
+	}, value.Export())
+
+	// the requests share one client
+	client := helper.module.browser
+
+	assert.NotNil(t, client)
+	assert.Same(t, client, helper.module.browserClient())
+
+	_, err = helper.vu.Runtime().RunString(`mock.intercept({})`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+	assert.ErrorContains(t, err, "request interception is not supported by this browser version")
+}
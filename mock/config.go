@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.k6.io/k6/lib/types"
+)
+
+// configKey is the name of the extension's section in the ext field of k6 options.
+const configKey = "mock"
+
+// config holds the settings of options.ext.mock, the defaults of mock definitions' options.
+type config struct {
+	Sync      *bool          `json:"sync"`
+	Diagnose  bool           `json:"diagnose"`
+	Host      string         `json:"host"`
+	BasePort  int64          `json:"basePort"`
+	HTTP2     interface{}    `json:"http2"`
+	Scope     string         `json:"scope"`
+	LogLevel  string         `json:"logLevel"`
+	BodyLimit int64          `json:"bodyLimit"`
+	FileLimit int64          `json:"fileLimit"`
+	Strict    bool           `json:"strict"`
+	TLS       bool           `json:"tls"`
+	Recording string         `json:"recording"`
+	Delay     types.Duration `json:"delay"`
+
+	proto string
+	level logrus.Level
+}
+
+const maxPortNumber = 65535
+
+// parseConfig parses and validates the mock section of k6 options. Unknown settings are rejected.
+func parseConfig(data []byte) (*config, error) {
+	cfg := new(config)
+
+	if len(data) == 0 {
+		return cfg, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%w: options.ext.%s: %s", errInvalidArg, configKey, err.Error())
+	}
+
+	switch value := cfg.HTTP2.(type) {
+	case nil:
+	case bool:
+		if value {
+			cfg.proto = protoH2
+		}
+	case string:
+		cfg.proto = value
+	default:
+		return nil, fmt.Errorf("%w: options.ext.%s.http2 must be boolean or string", errInvalidArg, configKey)
+	}
+
+	if !validProto(cfg.proto) {
+		return nil, fmt.Errorf("%w: options.ext.%s.http2 must be %q or %q", errInvalidArg, configKey, protoH2, protoH2C)
+	}
+
+	if cfg.Scope != "" && cfg.Scope != scopeVU && cfg.Scope != scopeIteration {
+		return nil, fmt.Errorf("%w: options.ext.%s.scope must be %q or %q", errInvalidArg, configKey, scopeVU, scopeIteration)
+	}
+
+	if cfg.BasePort < 0 || cfg.BasePort > maxPortNumber {
+		return nil, fmt.Errorf("%w: options.ext.%s.basePort out of range: %d", errInvalidArg, configKey, cfg.BasePort)
+	}
+
+	if cfg.TLS {
+		proto, ok := tlsProto(cfg.proto)
+		if !ok {
+			return nil, fmt.Errorf("%w: options.ext.%s.tls cannot be used with h2c", errInvalidArg, configKey)
+		}
+
+		cfg.proto = proto
+	}
+
+	if cfg.Delay < 0 {
+		return nil, fmt.Errorf("%w: options.ext.%s.delay cannot be negative", errInvalidArg, configKey)
+	}
+
+	if cfg.BodyLimit < 0 || cfg.FileLimit < 0 {
+		return nil, fmt.Errorf("%w: options.ext.%s body and file limits cannot be negative", errInvalidArg, configKey)
+	}
+
+	if len(cfg.LogLevel) != 0 {
+		level, err := logrus.ParseLevel(cfg.LogLevel)
+		if err != nil {
+			return nil, fmt.Errorf("%w: options.ext.%s.logLevel: %s", errInvalidArg, configKey, err.Error())
+		}
+
+		cfg.level = level
+	}
+
+	return cfg, nil
+}
+
+// apply sets the options not given in the mock definition to the configured defaults.
+func (cfg *config) apply(opts *options) {
+	given := func(name string) bool {
+		_, found := opts.given[name]
+
+		return found
+	}
+
+	if !given("sync") && cfg.Sync != nil {
+		if opts.given == nil {
+			opts.given = make(map[string]struct{})
+		}
+
+		opts.sync, opts.given["sync"] = *cfg.Sync, struct{}{}
+	}
+
+	if !given("diagnose") {
+		opts.diagnose = cfg.Diagnose
+	}
+
+	// the listener defaults are for mocks listening on a port chosen by the extension
+	listener := !given("port") && !opts.unix && !opts.memory
+
+	if !given("host") && listener {
+		opts.host = cfg.Host
+	}
+
+	if !given("basePort") && listener {
+		opts.basePort = cfg.BasePort
+	}
+
+	if !given("http2") && !given("tls") {
+		opts.proto = cfg.proto
+	}
+
+	if !given("strict") {
+		opts.strict = cfg.Strict
+	}
+
+	if !given("delay") {
+		opts.delay = time.Duration(cfg.Delay)
+	}
+
+	if !given("scope") {
+		opts.scope = cfg.Scope
+	}
+
+	if !given("bodyLimit") {
+		opts.bodyLimit = cfg.BodyLimit
+	}
+
+	if !given("fileLimit") {
+		opts.fileLimit = cfg.FileLimit
+	}
+}
+
+// configLoader parses the mock section of k6 options once per test run, every VU gets the same options.
+type configLoader struct {
+	once sync.Once
+	cfg  *config
+	err  error
+}
+
+// load returns the parsed configuration, the data is parsed on first call only.
+func (loader *configLoader) load(data []byte) (*config, error) {
+	loader.once.Do(func() {
+		loader.cfg, loader.err = parseConfig(data)
+	})
+
+	return loader.cfg, loader.err
+}
+
+// configure reads the mock section of k6 options and applies the defaults to the mock definitions
+// started in the init context. The options are available to the extension only in VU context,
+// so the configuration is read when the VU starts its first iteration (or on first use in setup and teardown).
+func (mod *Module) configure() error {
+	if mod.config != nil {
+		return nil
+	}
+
+	state := mod.vu.State()
+	if state == nil {
+		return nil
+	}
+
+	cfg, err := mod.loader.load(state.Options.External[configKey])
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.LogLevel) != 0 && mod.logLevel != nil {
+		mod.logLevel.SetLevel(cfg.level)
+	}
+
+	if len(cfg.Recording) != 0 {
+		if err := mod.recorder.open(cfg.Recording); err != nil {
+			return err
+		}
+	}
+
+	mod.config = cfg
+
+	return mod.applyDefaults(int64(state.VUID))
+}
+
+// leveledLogger returns a logger writing to the same output as logger, with its own level.
+// The level is initially the same as the level of logger. Unknown logger types are returned as is,
+// without level control.
+func leveledLogger(logger logrus.FieldLogger) (logrus.FieldLogger, *logrus.Logger) {
+	var (
+		base *logrus.Logger
+		data logrus.Fields
+	)
+
+	switch value := logger.(type) {
+	case *logrus.Entry:
+		base, data = value.Logger, value.Data
+	case *logrus.Logger:
+		base = value
+	default:
+		return logger, nil
+	}
+
+	leveled := &logrus.Logger{ // nolint:exhaustruct
+		Out:          base.Out,
+		Hooks:        base.Hooks,
+		Formatter:    base.Formatter,
+		ReportCaller: base.ReportCaller,
+		Level:        base.GetLevel(),
+		ExitFunc:     base.ExitFunc,
+	}
+
+	return leveled.WithFields(data), leveled
+}
@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/types"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := parseConfig(nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, protoHTTP1, cfg.proto)
+
+	cfg, err = parseConfig([]byte(`{"sync":true,"diagnose":true,"basePort":8000,"http2":true,"scope":"iteration","logLevel":"error"}`))
+
+	assert.NoError(t, err)
+	assert.True(t, *cfg.Sync)
+	assert.True(t, cfg.Diagnose)
+	assert.Equal(t, int64(8000), cfg.BasePort)
+	assert.Equal(t, protoH2, cfg.proto)
+	assert.Equal(t, scopeIteration, cfg.Scope)
+	assert.Equal(t, logrus.ErrorLevel, cfg.level)
+
+	cfg, err = parseConfig([]byte(`{"http2":"h2c"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, protoH2C, cfg.proto)
+
+	cfg, err = parseConfig([]byte(`{"strict":true,"delay":"250ms"}`))
+
+	assert.NoError(t, err)
+	assert.True(t, cfg.Strict)
+	assert.Equal(t, types.Duration(250*time.Millisecond), cfg.Delay)
+
+	cfg, err = parseConfig([]byte(`{"delay":100}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.Duration(100*time.Millisecond), cfg.Delay)
+
+	cfg, err = parseConfig([]byte(`{"tls":true,"recording":"recordings"}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, protoHTTPS, cfg.proto)
+	assert.Equal(t, "recordings", cfg.Recording)
+
+	cfg, err = parseConfig([]byte(`{"tls":true,"http2":true}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, protoH2, cfg.proto)
+
+	for _, data := range []string{
+		`{"unknown":true}`,
+		`{"strict":"yes"}`,
+		`{"delay":"soon"}`,
+		`{"tls":true,"http2":"h2c"}`,
+		`{"recording":1}`,
+		`{"delay":-1}`,
+		`{"sync":"yes"}`,
+		`{"http2":"h3"}`,
+		`{"http2":1}`,
+		`{"scope":"test"}`,
+		`{"basePort":70000}`,
+		`{"logLevel":"loud"}`,
+		`{"bodyLimit":-1}`,
+		`[]`,
+	} {
+		_, err := parseConfig([]byte(data))
+
+		assert.ErrorIs(t, err, errInvalidArg, data)
+	}
+}
+
+func TestConfigApply(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := parseConfig([]byte(`{"sync":true,"diagnose":true,"host":"0.0.0.0","basePort":8000,"http2":"h2c","fileLimit":1024,"strict":true,"delay":50}`))
+
+	assert.NoError(t, err)
+
+	opts := new(options)
+
+	cfg.apply(opts)
+
+	assert.True(t, opts.sync)
+	assert.True(t, opts.diagnose)
+	assert.Equal(t, "0.0.0.0", opts.host)
+	assert.Equal(t, int64(8000), opts.basePort)
+	assert.Equal(t, protoH2C, opts.proto)
+	assert.Equal(t, int64(1024), opts.fileLimit)
+	assert.True(t, opts.strict)
+	assert.Equal(t, 50*time.Millisecond, opts.delay)
+
+	opts = &options{sync: false, port: 9000, tls: true, given: map[string]struct{}{"sync": {}, "port": {}, "strict": {}, "delay": {}, "tls": {}}} // nolint:exhaustruct,lll
+
+	cfg.apply(opts)
+
+	assert.False(t, opts.sync)
+	assert.Equal(t, protoHTTP1, opts.proto)
+	assert.False(t, opts.strict)
+	assert.Zero(t, opts.delay)
+	assert.Empty(t, opts.host)
+	assert.Zero(t, opts.basePort)
+	assert.Equal(t, int64(9000), opts.port)
+
+	opts = &options{memory: true, given: map[string]struct{}{"memory": {}}} // nolint:exhaustruct
+
+	cfg.apply(opts)
+
+	assert.Empty(t, opts.host)
+	assert.Zero(t, opts.basePort)
+	assert.NoError(t, checkDialed("https://example.com", opts, "memory"))
+}
+
+func TestLeveledLogger(t *testing.T) {
+	t.Parallel()
+
+	base, hook := test.NewNullLogger()
+
+	logger, level := leveledLogger(base.WithField("module", "mock"))
+
+	assert.NotNil(t, level)
+
+	logger.Info("before")
+
+	level.SetLevel(logrus.WarnLevel)
+
+	logger.Info("after")
+	logger.Warn("warning")
+
+	assert.Len(t, hook.AllEntries(), 2)
+	assert.Equal(t, "mock", hook.LastEntry().Data["module"])
+	assert.Equal(t, logrus.InfoLevel, base.GetLevel())
+}
+
+func TestModuleConfigure(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.get("/", (req, res) => res.text("mock"))
+})
+`)
+
+	assert.NoError(t, err)
+	assert.Nil(t, helper.module.config)
+
+	moveToHTTPContext(helper)
+
+	helper.vu.StateField.Options.External = map[string]json.RawMessage{configKey: []byte(`{"unknown":true}`)}
+
+	_, err = helper.vu.Runtime().RunString(`http.get("https://example.com/")`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	// the options are parsed once per test run
+	helper.vu.StateField.Options.External = nil
+
+	_, err = helper.vu.Runtime().RunString(`http.get("https://example.com/")`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	helper = newHelper(t)
+
+	moveToHTTPContext(helper)
+
+	helper.vu.StateField.Options.External = map[string]json.RawMessage{configKey: []byte(`{"sync":true,"diagnose":true}`)}
+
+	value, err := helper.vu.Runtime().RunString(`
+mock("https://example.net", app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { diagnose: false })
+
+http.get("https://example.net/").body
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mock", value.String())
+	assert.True(t, *helper.module.config.Sync)
+	assert.False(t, helper.module.servers["https://example.net"].diagnose)
+
+	_, err = helper.vu.Runtime().RunString(`mock("https://example.org", app => {}, { delay: -1 })`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	helper = newHelper(t)
+
+	moveToHTTPContext(helper)
+
+	helper.vu.StateField.Options.External = map[string]json.RawMessage{configKey: []byte(`{"tls":true}`)}
+
+	value, err = helper.vu.Runtime().RunString(`
+mock("https://example.net", app => {
+  app.get("/", (req, res) => res.text("mock"))
+})
+
+http.get("https://example.net/").body
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mock", value.String())
+	assert.Equal(t, protoHTTPS, helper.module.servers["https://example.net"].proto)
+
+	helper.module.teardown()
+}
+
+func TestModuleStrict(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	moveToHTTPContext(helper)
+
+	helper.vu.StateField.Options.External = map[string]json.RawMessage{configKey: []byte(`{"strict":true}`)}
+
+	value, err := helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.get("/", (req, res) => res.text("mock"))
+})
+
+mock("https://example.net", app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { strict: false })
+
+http.get("https://example.net/missing").status
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(404), value.ToInteger())
+	assert.True(t, helper.module.servers["https://example.com"].strict)
+
+	value, err = helper.vu.Runtime().RunString(`http.get("https://example.com/").body`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mock", value.String())
+
+	// unmatched requests of strict mocks fail the request call
+	_, err = helper.vu.Runtime().RunString(`http.get("https://example.com/missing")`)
+
+	assert.ErrorIs(t, err, errUnmatched)
+	assert.ErrorContains(t, err, "GET https://example.com/missing")
+
+	helper.module.teardown()
+}
+
+func TestModuleApplyDefaults(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	local := event.NewEventSystem(10, logrus.StandardLogger())
+
+	helper.vu.EventsField = common.Events{Global: event.NewEventSystem(10, logrus.StandardLogger()), Local: local}
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://failing.example.com", app => { throw new Error("failing") })
+`)
+
+	assert.Error(t, err)
+	assert.NotContains(t, helper.module.servers, "https://failing.example.com")
+
+	_, err = helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.get("/", (req, res) => res.text("mock"))
+})
+
+mock("https://example.net", app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { diagnose: false, http2: false })
+`)
+
+	assert.NoError(t, err)
+
+	// init context mocks are started eagerly, without the defaults
+	assert.Len(t, helper.module.servers, 2)
+	assert.Contains(t, helper.module.lookup, "https://example.com")
+
+	unchanged := helper.module.servers["https://example.net"]
+
+	moveToHTTPContext(helper)
+
+	helper.vu.StateField.Options.External = map[string]json.RawMessage{
+		configKey: []byte(`{"sync":true,"diagnose":true,"http2":"h2c","bodyLimit":1024}`),
+	}
+
+	wait := local.Emit(&event.Event{Type: event.IterStart}) // nolint:exhaustruct
+	assert.NoError(t, wait(context.Background()))
+
+	assert.NotNil(t, helper.module.config)
+	assert.Empty(t, helper.module.initDefs)
+
+	srv := helper.module.servers["https://example.com"]
+
+	assert.True(t, srv.diagnose)
+	assert.Equal(t, protoH2C, srv.proto)
+	assert.Equal(t, modeSync, srv.mode)
+	assert.NotContains(t, helper.module.lookup, "https://example.com")
+	assert.NotNil(t, helper.module.dialers.get("https://example.com"))
+
+	assert.Same(t, unchanged, helper.module.servers["https://example.net"])
+	assert.Equal(t, modeSync, unchanged.mode)
+
+	value, err := helper.vu.Runtime().RunString(`
+[ http.get("https://example.com/").body, http.get("https://example.net/").body ]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"mock", "mock"}, value.Export())
+
+	helper.module.teardown()
+}
+
+func TestModuleApplyDefaultsInvalid(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	local := event.NewEventSystem(10, logrus.StandardLogger())
+
+	helper.vu.EventsField = common.Events{Global: event.NewEventSystem(10, logrus.StandardLogger()), Local: local}
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.get("/", (req, res) => res.text("mock"))
+})
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	helper.vu.StateField.Options.External = map[string]json.RawMessage{configKey: []byte(`{"basePort":-1}`)}
+
+	wait := local.Emit(&event.Event{Type: event.IterStart}) // nolint:exhaustruct
+	assert.NoError(t, wait(context.Background()))
+
+	assert.Nil(t, helper.module.config)
+
+	_, err = helper.vu.Runtime().RunString(`http.get("https://example.com/")`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	helper.module.teardown()
+}
+
+func TestModuleRecording(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	dir := t.TempDir()
+
+	moveToHTTPContext(helper)
+
+	helper.vu.StateField.Options.External = map[string]json.RawMessage{
+		configKey: []byte(`{"recording":` + strconv.Quote(dir) + `}`),
+	}
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.get("/", (req, res) => res.text("mock"))
+})
+
+http.get("https://example.com/")
+http.get("https://example.com/missing")
+`)
+
+	assert.NoError(t, err)
+	assert.NoError(t, helper.module.recorder.close())
+
+	data, err := os.ReadFile(filepath.Join(dir, recordingFile)) // nolint:gosec
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"target":"https://example.com","method":"GET","path":"/","route":"/","status":200`)
+	assert.Contains(t, string(data), `"target":"https://example.com","method":"GET","path":"/missing","status":404`)
+
+	helper.module.teardown()
+}
@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/sobek"
+)
+
+// defaultCORSMethods are the methods allowed by default in preflight responses.
+var defaultCORSMethods = []string{ // nolint:gochecknoglobals
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodPost,
+	http.MethodDelete,
+}
+
+// corsPolicy holds the CORS settings of a cors middleware.
+// Preflight requests are answered by the middleware, other responses are extended with CORS headers.
+type corsPolicy struct {
+	origins     []*regexp.Regexp
+	any         bool
+	methods     []string
+	headers     []string
+	exposed     []string
+	credentials bool
+	maxAge      int64
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin header for given origin,
+// or empty string if the origin is not allowed.
+func (policy *corsPolicy) allowOrigin(origin string) string {
+	if len(origin) == 0 {
+		return ""
+	}
+
+	if policy.any {
+		if policy.credentials {
+			return origin
+		}
+
+		return "*"
+	}
+
+	for _, re := range policy.origins {
+		if re.MatchString(origin) {
+			return origin
+		}
+	}
+
+	return ""
+}
+
+// apply sets the CORS headers of a response to a request from given origin.
+func (policy *corsPolicy) apply(header http.Header, origin string) {
+	allowed := policy.allowOrigin(origin)
+
+	if allowed != "*" {
+		header.Add("Vary", "Origin")
+	}
+
+	if len(allowed) == 0 {
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", allowed)
+
+	if policy.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if len(policy.exposed) != 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(policy.exposed, ", "))
+	}
+}
+
+func isPreflight(r *http.Request) bool {
+	return isPreflightRequest(r.Method, r.Header)
+}
+
+func isPreflightRequest(method string, header http.Header) bool {
+	return method == http.MethodOptions &&
+		len(header.Get("Origin")) != 0 &&
+		len(header.Get("Access-Control-Request-Method")) != 0
+}
+
+// preflight sets the headers of the response to a preflight request with given request headers.
+// Not allowed origins and methods get no CORS headers, so the browser refuses the actual request.
+// The response status is 204 in all cases.
+func (policy *corsPolicy) preflight(header http.Header, request http.Header) {
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	allowed := policy.allowOrigin(request.Get("Origin"))
+	method := strings.ToUpper(request.Get("Access-Control-Request-Method"))
+
+	if len(allowed) == 0 || !policy.allowMethod(method) {
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", allowed)
+	header.Set("Access-Control-Allow-Methods", strings.Join(policy.methods, ", "))
+
+	if len(policy.headers) != 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(policy.headers, ", "))
+	} else if requested := request.Get("Access-Control-Request-Headers"); len(requested) != 0 {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+
+	if policy.credentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if policy.maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.FormatInt(policy.maxAge, 10))
+	}
+}
+
+func (policy *corsPolicy) allowMethod(method string) bool {
+	for _, m := range policy.methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newCORSPolicy returns the CORS policy of given options. Origins may contain * wildcards.
+func (mod *Module) newCORSPolicy(value sobek.Value) *corsPolicy {
+	policy := &corsPolicy{any: true, methods: defaultCORSMethods} // nolint:exhaustruct
+
+	obj, isObj := value.(*sobek.Object)
+	if !isObj {
+		return policy
+	}
+
+	list := func(name string) ([]string, bool) {
+		v := obj.Get(name)
+		if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+			return nil, false
+		}
+
+		var items []string
+
+		if v.ExportType().Kind() == reflect.String {
+			items = strings.Split(v.String(), ",")
+		} else {
+			var values []string
+
+			if err := mod.runtime().ExportTo(v, &values); err != nil {
+				mod.throwf("cors: %s must be string or array of strings", errInvalidArg, name)
+			}
+
+			items = values
+		}
+
+		result := make([]string, 0, len(items))
+
+		for _, item := range items {
+			if item = strings.TrimSpace(item); len(item) != 0 {
+				result = append(result, item)
+			}
+		}
+
+		return result, true
+	}
+
+	if origins, found := list("origin"); found {
+		policy.any = false
+
+		for _, origin := range origins {
+			if origin == "*" {
+				policy.any = true
+			}
+
+			policy.origins = append(policy.origins, globRegexp(origin))
+		}
+	}
+
+	if methods, found := list("methods"); found {
+		policy.methods = make([]string, 0, len(methods))
+
+		for _, method := range methods {
+			policy.methods = append(policy.methods, strings.ToUpper(method))
+		}
+	}
+
+	policy.headers, _ = list("allowedHeaders")
+	policy.exposed, _ = list("exposedHeaders")
+
+	if v := obj.Get("credentials"); v != nil {
+		policy.credentials = v.ToBoolean()
+	}
+
+	if v := obj.Get("maxAge"); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+		policy.maxAge = v.ToInteger()
+	}
+
+	return policy
+}
+
+// corsRequestHeaders are the request headers used by the cors middleware.
+var corsRequestHeaders = []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} // nolint:gochecknoglobals,lll
+
+// cors returns a middleware enabling CORS on the application (or route) using it.
+// The middleware answers preflight requests and sets the CORS headers of other responses.
+// Used by a mock definition's application, the unmatched responses written by the mock server itself
+// get the CORS headers too. Authentication failures get them if the cors middleware is used before
+// the authentication middleware.
+func (mod *Module) cors(value sobek.Value) sobek.Value {
+	policy := mod.newCORSPolicy(value)
+
+	middleware := mod.runtime().ToValue(func(call sobek.FunctionCall) sobek.Value {
+		req := call.Argument(0).ToObject(mod.runtime())
+		res := call.Argument(1).ToObject(mod.runtime())
+
+		request := make(http.Header, len(corsRequestHeaders))
+
+		for _, name := range corsRequestHeaders {
+			if v := mod.invoke(req, "get", name).String(); len(v) != 0 {
+				request.Set(name, v)
+			}
+		}
+
+		header := make(http.Header)
+
+		if isPreflightRequest(req.Get("method").String(), request) {
+			policy.preflight(header, request)
+			mod.setHeaders(res, header)
+			mod.invoke(res, "status", http.StatusNoContent)
+
+			return sobek.Undefined()
+		}
+
+		policy.apply(header, request.Get("Origin"))
+		mod.setHeaders(res, header)
+
+		if next, ok := sobek.AssertFunction(call.Argument(2)); ok {
+			if _, err := next(sobek.Undefined()); err != nil {
+				mod.throw(err)
+			}
+		}
+
+		return sobek.Undefined()
+	}).(*sobek.Object) // nolint:forcetypeassert
+
+	mod.mark(middleware, &marker{owner: mod.defining, cors: policy}) // nolint:exhaustruct
+
+	return middleware
+}
+
+// setHeaders adds the headers to the response object of the application.
+func (mod *Module) setHeaders(res *sobek.Object, header http.Header) {
+	for name, values := range header {
+		for _, value := range values {
+			mod.invoke(res, "append", name, value)
+		}
+	}
+}
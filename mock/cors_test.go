@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORSPolicy(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	policy := helper.module.newCORSPolicy(nil)
+
+	assert.Equal(t, "*", policy.allowOrigin("https://example.com"))
+	assert.Empty(t, policy.allowOrigin(""))
+
+	value, err := helper.vu.Runtime().RunString(`({
+  origin: ["https://app.example.com", "http://localhost:*"],
+  methods: "get, post",
+  allowedHeaders: ["Content-Type", "Authorization"],
+  exposedHeaders: "X-Total",
+  credentials: true,
+  maxAge: 600,
+})`)
+
+	assert.NoError(t, err)
+
+	policy = helper.module.newCORSPolicy(value)
+
+	assert.Equal(t, "https://app.example.com", policy.allowOrigin("https://app.example.com"))
+	assert.Equal(t, "http://localhost:3000", policy.allowOrigin("http://localhost:3000"))
+	assert.Empty(t, policy.allowOrigin("https://example.com"))
+	assert.Equal(t, []string{"GET", "POST"}, policy.methods)
+
+	header := http.Header{}
+
+	policy.apply(header, "https://app.example.com")
+
+	assert.Equal(t, "https://app.example.com", header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", header.Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Total", header.Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", header.Get("Vary"))
+
+	preflight := func(origin string, method string) http.Header {
+		req := httptest.NewRequest(http.MethodOptions, "/items", nil)
+
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+
+		assert.True(t, isPreflight(req))
+
+		header := http.Header{}
+
+		policy.preflight(header, req.Header)
+
+		return header
+	}
+
+	header = preflight("https://app.example.com", "post")
+
+	assert.Equal(t, "https://app.example.com", header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", header.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", header.Get("Access-Control-Max-Age"))
+
+	header = preflight("https://app.example.com", "DELETE")
+
+	assert.Empty(t, header.Get("Access-Control-Allow-Origin"))
+
+	header = preflight("https://example.com", "GET")
+
+	assert.Empty(t, header.Get("Access-Control-Allow-Origin"))
+
+	assert.False(t, isPreflight(httptest.NewRequest(http.MethodOptions, "/items", nil)))
+
+	_, err = helper.vu.Runtime().RunString(`mock.cors({ origin: 42 })`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
+
+func TestModuleCORS(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.use(mock.cors({ origin: "https://app.example.com", exposedHeaders: ["X-Total"] }))
+  app.get("/items", (req, res) => {
+    res.set("X-Total", "2")
+    res.json([1, 2])
+  })
+})
+
+mock("https://example.net", app => {
+  app.get("/items", (req, res) => res.json([]))
+})
+
+mock("https://example.org", app => {
+  app.use(mock.cors({ origin: "https://app.example.com" }))
+  app.use(mock.auth.bearer({ tokens: { secret: "user" } }))
+  app.get("/items", (req, res) => res.json([]))
+})
+
+const standalone = new Application({ sync: true })
+
+standalone.use(mock.cors({ origin: "https://app.example.com" }))
+standalone.get("/items", (req, res) => res.json([]))
+standalone.listen()
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+const origin = { headers: { "Origin": "https://app.example.com" } }
+const preflight = {
+  headers: { "Origin": "https://app.example.com", "Access-Control-Request-Method": "GET", "Access-Control-Request-Headers": "X-Trace" },
+}
+
+const res = [
+  http.options("https://example.com/items", null, preflight),
+  http.get("https://example.com/items", origin),
+  http.get("https://example.net/items", origin),
+  http.options("https://example.org/items", null, preflight),
+  http.get("https://example.org/items", origin),
+  http.options("http://" + standalone.host + "/items", null, preflight),
+  http.get("http://" + standalone.host + "/items", origin),
+]
+
+standalone.shutdown()
+
+res.map(r => [r.status, r.headers["Access-Control-Allow-Origin"] || "", r.headers["Access-Control-Allow-Headers"] || "",
+  r.headers["Access-Control-Expose-Headers"] || ""])
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		[]interface{}{int64(204), "https://app.example.com", "X-Trace", ""},
+		[]interface{}{int64(200), "https://app.example.com", "", "X-Total"},
+		[]interface{}{int64(200), "", "", ""},
+		[]interface{}{int64(204), "https://app.example.com", "X-Trace", ""},
+		[]interface{}{int64(401), "https://app.example.com", "", ""},
+		[]interface{}{int64(204), "https://app.example.com", "X-Trace", ""},
+		[]interface{}{int64(200), "https://app.example.com", "", ""},
+	}, value.Export())
+
+	// the cors middleware of https://example.org is removed together with the mock
+	assert.Len(t, helper.module.markers, 3)
+
+	_, err = helper.vu.Runtime().RunString(`unmock("https://example.org")`)
+
+	assert.NoError(t, err)
+	assert.Len(t, helper.module.markers, 2)
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/grafana/sobek"
+)
+
+// coverageEnv is the name of the environment variable containing the coverage report file name.
+const coverageEnv = "K6_MOCK_COVERAGE"
+
+// coverageLog collects hits of registered routes from all VUs.
+type coverageLog struct {
+	mu      sync.Mutex
+	targets map[string]map[route]*routeHits
+}
+
+type routeHits struct {
+	hits     int
+	statuses map[int]int
+}
+
+func newCoverageLog() *coverageLog {
+	return &coverageLog{targets: make(map[string]map[route]*routeHits)} // nolint:exhaustruct
+}
+
+// register adds the routes of a mock definition. Already registered routes keep their hits.
+func (clog *coverageLog) register(target string, routes []*route) {
+	clog.mu.Lock()
+	defer clog.mu.Unlock()
+
+	hits, found := clog.targets[target]
+	if !found {
+		hits = make(map[route]*routeHits)
+		clog.targets[target] = hits
+	}
+
+	for _, rte := range routes {
+		if _, found := hits[*rte]; !found {
+			hits[*rte] = &routeHits{statuses: make(map[int]int)} // nolint:exhaustruct
+		}
+	}
+}
+
+func (clog *coverageLog) hit(exc *exchange) {
+	if exc.route == nil {
+		return
+	}
+
+	clog.mu.Lock()
+	defer clog.mu.Unlock()
+
+	hits, found := clog.targets[exc.target][*exc.route]
+	if !found {
+		return
+	}
+
+	hits.hits++
+	hits.statuses[exc.status]++
+}
+
+type coverageReport struct {
+	Routes   int              `json:"routes"`
+	Hit      int              `json:"hit"`
+	Coverage float64          `json:"coverage"`
+	Mocks    []coverageTarget `json:"mocks"`
+}
+
+type coverageTarget struct {
+	Target   string          `json:"target"`
+	Routes   int             `json:"routes"`
+	Hit      int             `json:"hit"`
+	Coverage float64         `json:"coverage"`
+	Entries  []coverageRoute `json:"entries"`
+}
+
+type coverageRoute struct {
+	Method   string         `json:"method"`
+	Path     string         `json:"path"`
+	Hits     int            `json:"hits"`
+	Statuses map[string]int `json:"statuses"`
+}
+
+// report returns the coverage report, targets and routes are sorted.
+func (clog *coverageLog) report() *coverageReport {
+	clog.mu.Lock()
+	defer clog.mu.Unlock()
+
+	report := &coverageReport{Mocks: []coverageTarget{}} // nolint:exhaustruct
+
+	for target, hits := range clog.targets {
+		entry := coverageTarget{Target: target, Entries: []coverageRoute{}} // nolint:exhaustruct
+
+		for rte, rhits := range hits {
+			statuses := make(map[string]int, len(rhits.statuses))
+
+			for status, count := range rhits.statuses {
+				statuses[strconv.Itoa(status)] = count
+			}
+
+			entry.Entries = append(entry.Entries, coverageRoute{
+				Method:   rte.method,
+				Path:     rte.path,
+				Hits:     rhits.hits,
+				Statuses: statuses,
+			})
+
+			entry.Routes++
+
+			if rhits.hits != 0 {
+				entry.Hit++
+			}
+		}
+
+		sort.Slice(entry.Entries, func(i, j int) bool {
+			if entry.Entries[i].Path != entry.Entries[j].Path {
+				return entry.Entries[i].Path < entry.Entries[j].Path
+			}
+
+			return entry.Entries[i].Method < entry.Entries[j].Method
+		})
+
+		entry.Coverage = ratio(entry.Hit, entry.Routes)
+		report.Routes += entry.Routes
+		report.Hit += entry.Hit
+		report.Mocks = append(report.Mocks, entry)
+	}
+
+	sort.Slice(report.Mocks, func(i, j int) bool { return report.Mocks[i].Target < report.Mocks[j].Target })
+
+	report.Coverage = ratio(report.Hit, report.Routes)
+
+	return report
+}
+
+func ratio(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(part) / float64(total)
+}
+
+// write saves the coverage report as JSON file.
+func (clog *coverageLog) write(filename string) error {
+	data, err := json.MarshalIndent(clog.report(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, 0o600) // nolint:gomnd
+}
+
+// coverage returns the coverage report for JavaScript (for example to be used in handleSummary).
+func (mod *Module) coverage() sobek.Value {
+	data, err := json.Marshal(mod.coverageLog.report())
+	if err != nil {
+		mod.throw(err)
+	}
+
+	var report interface{}
+
+	if err := json.Unmarshal(data, &report); err != nil {
+		mod.throw(err)
+	}
+
+	return mod.runtime().ToValue(report)
+}
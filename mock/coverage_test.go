@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoverageLog(t *testing.T) {
+	t.Parallel()
+
+	clog := newCoverageLog()
+	users := &route{method: http.MethodGet, path: "/users/:id"}
+	orders := &route{method: http.MethodPost, path: "/orders"}
+
+	clog.register("https://example.com", []*route{users, orders})
+	clog.register("https://example.com", []*route{{method: http.MethodGet, path: "/users/:id"}})
+
+	clog.hit(&exchange{target: "https://example.com", route: users, status: http.StatusOK})
+	clog.hit(&exchange{target: "https://example.com", route: users, status: http.StatusOK})
+	clog.hit(&exchange{target: "https://example.com", route: users, status: http.StatusNotFound})
+	clog.hit(&exchange{target: "https://example.com", status: http.StatusNotFound})
+	clog.hit(&exchange{target: "https://other.example.com", route: users, status: http.StatusOK})
+
+	report := clog.report()
+
+	assert.Equal(t, 2, report.Routes)
+	assert.Equal(t, 1, report.Hit)
+	assert.Equal(t, 0.5, report.Coverage)
+	assert.Len(t, report.Mocks, 1)
+
+	assert.Equal(t, []coverageRoute{
+		{Method: http.MethodPost, Path: "/orders", Hits: 0, Statuses: map[string]int{}},
+		{Method: http.MethodGet, Path: "/users/:id", Hits: 3, Statuses: map[string]int{"200": 2, "404": 1}},
+	}, report.Mocks[0].Entries)
+
+	assert.Zero(t, newCoverageLog().report().Coverage)
+}
+
+func TestCoverageLogWrite(t *testing.T) {
+	t.Parallel()
+
+	clog := newCoverageLog()
+
+	clog.register("https://example.com", []*route{{method: http.MethodGet, path: "/"}})
+
+	filename := filepath.Join(t.TempDir(), "coverage.json")
+
+	assert.NoError(t, clog.write(filename))
+
+	data, err := os.ReadFile(filename)
+
+	assert.NoError(t, err)
+
+	var report coverageReport
+
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, 1, report.Routes)
+	assert.Equal(t, "https://example.com", report.Mocks[0].Target)
+
+	assert.Error(t, clog.write(t.TempDir()))
+}
+
+func TestModuleCoverage(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://coverage.example.com", app => {
+  app.get("/hit", (req, res) => res.text("hit"))
+  app.get("/miss", (req, res) => res.text("miss"))
+}, { sync: true })
+`)
+
+	assert.NoError(t, err)
+
+	resp, err := req.Get(helper.module.lookup["https://coverage.example.com"] + "/hit")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.GetStatusCode())
+
+	value, err := helper.vu.Runtime().RunString(`
+const report = mock.coverage()
+const entry = report.mocks[0]
+;[report.routes, report.hit, entry.target, entry.entries[0].path, entry.entries[0].hits, entry.entries[0].statuses["200"]]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(2), int64(1), "https://coverage.example.com", "/hit", int64(1), int64(1)}, value.Export())
+}
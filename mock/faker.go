@@ -0,0 +1,357 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+const seedEnv = "K6_MOCK_SEED"
+
+var (
+	firstNames = []string{
+		"Alice", "Bob", "Carol", "David", "Emma", "Frank", "Grace", "Henry", "Isabel", "Jack",
+		"Katalin", "Liam", "Maria", "Noah", "Olivia", "Peter", "Quinn", "Rose", "Samuel", "Tamara",
+		"Ugo", "Vera", "William", "Xenia", "Yusuf", "Zoe",
+	}
+	lastNames = []string{
+		"Anderson", "Brown", "Clark", "Davis", "Evans", "Fischer", "Garcia", "Harris", "Ito", "Johnson",
+		"Kovacs", "Lee", "Miller", "Nagy", "Olsen", "Parker", "Quinn", "Rossi", "Smith", "Taylor",
+		"Umarov", "Varga", "Wilson", "Xu", "Young", "Zimmerman",
+	}
+	streets = []string{
+		"Main Street", "Oak Avenue", "Maple Road", "Park Lane", "River Road", "Hill Street",
+		"Church Street", "Lake View", "Station Road", "Mill Lane", "Forest Drive", "Garden Way",
+	}
+	cities = []string{
+		"Springfield", "Riverside", "Fairview", "Greenville", "Franklin", "Clinton", "Madison",
+		"Georgetown", "Salem", "Bristol", "Oxford", "Ashland",
+	}
+	countries = []string{
+		"Australia", "Austria", "Brazil", "Canada", "France", "Germany", "Hungary", "Italy",
+		"Japan", "Netherlands", "Spain", "Sweden", "United Kingdom", "United States",
+	}
+	domains = []string{"example.com", "example.net", "example.org", "mail.test", "inbox.test"}
+	lorem   = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor
+	incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco
+	laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit esse
+	cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui officia
+	deserunt mollit anim id est laborum`)
+
+	fakerMinDate = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	fakerMaxDate = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+const (
+	defaultLoremWords    = 8
+	defaultFloatMax      = 1.0
+	defaultIntMax        = 100
+	maxStreetNumber      = 999
+	zipCodeRange         = 90000
+	zipCodeMin           = 10000
+	sentencesInParagraph = 4
+)
+
+type faker struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func newFaker(seed int64) *faker {
+	return &faker{rnd: rand.New(rand.NewSource(seed))} // nolint:exhaustruct,gosec
+}
+
+func (f *faker) reseed(seed int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rnd = rand.New(rand.NewSource(seed)) // nolint:gosec
+}
+
+func (f *faker) intn(n int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.rnd.Intn(n)
+}
+
+func (f *faker) float64() float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.rnd.Float64()
+}
+
+func (f *faker) pickString(from []string) string {
+	return from[f.intn(len(from))]
+}
+
+// between returns a number in the inclusive range. The size of the range is computed in uint64,
+// so ranges wider than the int63 range (e.g. math.MinInt64..math.MaxInt64) are not overflowed.
+func (f *faker) between(min, max int64) int64 {
+	if max <= min {
+		return min
+	}
+
+	span := uint64(max) - uint64(min)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if span < math.MaxInt64 {
+		return min + f.rnd.Int63n(int64(span)+1)
+	}
+
+	// the range does not fit Int63n, values outside of it are rejected (less than half of the values)
+	for {
+		if value := f.rnd.Uint64(); value <= span {
+			return min + int64(value)
+		}
+	}
+}
+
+func (f *faker) uuid() string {
+	buff := make([]byte, 16) // nolint:gomnd
+
+	f.mu.Lock()
+	f.rnd.Read(buff) // nolint:errcheck
+	f.mu.Unlock()
+
+	buff[6] = (buff[6] & 0x0f) | 0x40 // nolint:gomnd
+	buff[8] = (buff[8] & 0x3f) | 0x80 // nolint:gomnd
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", buff[0:4], buff[4:6], buff[6:8], buff[8:10], buff[10:])
+}
+
+func (f *faker) firstName() string {
+	return f.pickString(firstNames)
+}
+
+func (f *faker) lastName() string {
+	return f.pickString(lastNames)
+}
+
+func (f *faker) name() string {
+	return f.firstName() + " " + f.lastName()
+}
+
+func (f *faker) email() string {
+	local := strings.ToLower(f.firstName() + "." + f.lastName())
+
+	return local + "@" + f.pickString(domains)
+}
+
+func (f *faker) street() string {
+	return strconv.Itoa(f.intn(maxStreetNumber)+1) + " " + f.pickString(streets)
+}
+
+func (f *faker) city() string {
+	return f.pickString(cities)
+}
+
+func (f *faker) zip() string {
+	return strconv.Itoa(zipCodeMin + f.intn(zipCodeRange))
+}
+
+func (f *faker) country() string {
+	return f.pickString(countries)
+}
+
+func (f *faker) address() map[string]string {
+	return map[string]string{
+		"street":  f.street(),
+		"city":    f.city(),
+		"zip":     f.zip(),
+		"country": f.country(),
+	}
+}
+
+func (f *faker) words(count int) string {
+	if count <= 0 {
+		count = defaultLoremWords
+	}
+
+	all := make([]string, count)
+
+	for idx := range all {
+		all[idx] = f.pickString(lorem)
+	}
+
+	return strings.Join(all, " ")
+}
+
+func (f *faker) sentence(count int) string {
+	text := f.words(count)
+
+	return strings.ToUpper(text[:1]) + text[1:] + "."
+}
+
+func (f *faker) paragraph(count int) string {
+	if count <= 0 {
+		count = sentencesInParagraph
+	}
+
+	all := make([]string, count)
+
+	for idx := range all {
+		all[idx] = f.sentence(0)
+	}
+
+	return strings.Join(all, " ")
+}
+
+func (f *faker) date(from, to time.Time) string {
+	sec := f.between(from.Unix(), to.Unix())
+
+	return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
+
+func (mod *Module) fakerObject(fake *faker) *sobek.Object {
+	runtime := mod.runtime()
+	obj := runtime.NewObject()
+
+	intArg := func(call sobek.FunctionCall, idx int, def int64) int64 {
+		if arg := call.Argument(idx); !sobek.IsUndefined(arg) && !sobek.IsNull(arg) {
+			return arg.ToInteger()
+		}
+
+		return def
+	}
+
+	floatArg := func(call sobek.FunctionCall, idx int, def float64) float64 {
+		if arg := call.Argument(idx); !sobek.IsUndefined(arg) && !sobek.IsNull(arg) {
+			return arg.ToFloat()
+		}
+
+		return def
+	}
+
+	dateArg := func(call sobek.FunctionCall, idx int, def time.Time) time.Time {
+		arg := call.Argument(idx)
+		if sobek.IsUndefined(arg) || sobek.IsNull(arg) {
+			return def
+		}
+
+		if t, ok := arg.Export().(time.Time); ok {
+			return t
+		}
+
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, arg.String()); err == nil {
+				return t
+			}
+		}
+
+		mod.throwf("invalid date: %s", errInvalidArg, arg.String())
+
+		return def
+	}
+
+	mustSet := func(name string, value interface{}) {
+		if err := obj.Set(name, value); err != nil {
+			mod.throw(err)
+		}
+	}
+
+	mustSet("seed", func(call sobek.FunctionCall) sobek.Value {
+		fake.reseed(call.Argument(0).ToInteger())
+
+		return sobek.Undefined()
+	})
+
+	mustSet("uuid", fake.uuid)
+	mustSet("firstName", fake.firstName)
+	mustSet("lastName", fake.lastName)
+	mustSet("name", fake.name)
+	mustSet("email", fake.email)
+	mustSet("street", fake.street)
+	mustSet("city", fake.city)
+	mustSet("zip", fake.zip)
+	mustSet("country", fake.country)
+	mustSet("address", fake.address)
+
+	mustSet("words", func(call sobek.FunctionCall) sobek.Value {
+		return runtime.ToValue(fake.words(int(intArg(call, 0, defaultLoremWords))))
+	})
+
+	mustSet("sentence", func(call sobek.FunctionCall) sobek.Value {
+		return runtime.ToValue(fake.sentence(int(intArg(call, 0, defaultLoremWords))))
+	})
+
+	mustSet("paragraph", func(call sobek.FunctionCall) sobek.Value {
+		return runtime.ToValue(fake.paragraph(int(intArg(call, 0, sentencesInParagraph))))
+	})
+
+	mustSet("date", func(call sobek.FunctionCall) sobek.Value {
+		return runtime.ToValue(fake.date(dateArg(call, 0, fakerMinDate), dateArg(call, 1, fakerMaxDate)))
+	})
+
+	mustSet("int", func(call sobek.FunctionCall) sobek.Value {
+		if len(call.Arguments) < 2 { // nolint:gomnd
+			return runtime.ToValue(fake.between(0, intArg(call, 0, defaultIntMax)))
+		}
+
+		return runtime.ToValue(fake.between(intArg(call, 0, 0), intArg(call, 1, defaultIntMax)))
+	})
+
+	mustSet("float", func(call sobek.FunctionCall) sobek.Value {
+		min, max := 0.0, floatArg(call, 0, defaultFloatMax)
+
+		if len(call.Arguments) > 1 {
+			min, max = max, floatArg(call, 1, defaultFloatMax)
+		}
+
+		return runtime.ToValue(min + fake.float64()*(max-min))
+	})
+
+	mustSet("bool", func() bool {
+		return fake.intn(2) == 1 // nolint:gomnd
+	})
+
+	mustSet("pick", func(call sobek.FunctionCall) sobek.Value {
+		obj := call.Argument(0).ToObject(runtime)
+
+		length := obj.Get("length")
+		if length == nil || length.ToInteger() <= 0 {
+			return sobek.Undefined()
+		}
+
+		return obj.Get(strconv.Itoa(fake.intn(int(length.ToInteger()))))
+	})
+
+	return obj
+}
+
+// fakerSeed returns the seed used by the faker, taken from K6_MOCK_SEED environment variable if present.
+// The VU number is added to seed, so each VU generates different, but reproducible data.
+// k6 has no run-wide seed to reuse: randomSeed() only reseeds the Math.random of the VU and it cannot be read back.
+func (mod *Module) fakerSeed() int64 {
+	seed := time.Now().UnixNano()
+
+	if env := mod.vu.InitEnv(); env != nil && env.LookupEnv != nil {
+		if str, found := env.LookupEnv(seedEnv); found {
+			value, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				mod.throwf("%s must be an integer: %s", errInvalidArg, seedEnv, str)
+			}
+
+			seed = value
+		}
+	}
+
+	if v := mod.runtime().Get("__VU"); v != nil {
+		seed += v.ToInteger()
+	}
+
+	return seed
+}
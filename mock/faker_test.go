@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestFaker(t *testing.T) {
+	t.Parallel()
+
+	fake := newFaker(42)
+
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, fake.uuid())
+	assert.Regexp(t, `^[a-z]+\.[a-z]+@[a-z.]+$`, fake.email())
+	assert.Regexp(t, `^[A-Z][a-z]+ [A-Z][a-z]+$`, fake.name())
+	assert.Regexp(t, `^\d{5}$`, fake.zip())
+	assert.Len(t, fake.address(), 4)
+
+	for i := 0; i < 100; i++ {
+		n := fake.between(5, 7)
+
+		assert.GreaterOrEqual(t, n, int64(5))
+		assert.LessOrEqual(t, n, int64(7))
+	}
+
+	assert.Equal(t, int64(3), fake.between(3, 3))
+
+	// ranges wider than the int63 range do not overflow
+	assert.NotPanics(t, func() {
+		fake.between(math.MinInt64, math.MaxInt64)
+		fake.between(-1, math.MaxInt64)
+	})
+
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, fake.between(math.MaxInt64-1, math.MaxInt64), int64(math.MaxInt64-1))
+		assert.LessOrEqual(t, fake.between(math.MinInt64, math.MinInt64+1), int64(math.MinInt64+1))
+	}
+
+	from := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+	date, err := time.Parse(time.RFC3339, fake.date(from, to))
+
+	assert.NoError(t, err)
+	assert.False(t, date.Before(from))
+	assert.False(t, date.After(to))
+
+	assert.Regexp(t, `^[A-Z][a-z ]+\.$`, fake.sentence(3))
+	assert.NotEmpty(t, fake.paragraph(2))
+}
+
+func TestFakerSeed(t *testing.T) {
+	t.Parallel()
+
+	first, second := newFaker(1), newFaker(1)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, first.uuid(), second.uuid())
+		assert.Equal(t, first.name(), second.name())
+	}
+
+	first.reseed(2)
+	second.reseed(2)
+
+	assert.Equal(t, first.words(10), second.words(10))
+}
+
+func TestFakerSeedEnv(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	helper.vu.InitEnvField.LookupEnv = func(key string) (string, bool) {
+		if key == seedEnv {
+			return "1000", true
+		}
+
+		return "", false
+	}
+
+	assert.Equal(t, int64(1001), helper.module.fakerSeed())
+
+	helper.vu.InitEnvField.LookupEnv = func(key string) (string, bool) { return "foo", true }
+
+	assert.Panics(t, func() { helper.module.fakerSeed() })
+}
+
+type fakerSuite struct {
+	suiteBase
+}
+
+func TestFakerScript(t *testing.T) {
+	t.Parallel()
+
+	suite.Run(t, new(fakerSuite))
+}
+
+func (suite *fakerSuite) TestReproducible() {
+	value := suite.js(`
+// js
+mock.faker.seed(7)
+const first = [mock.faker.uuid(), mock.faker.name(), mock.faker.email(), mock.faker.int(1, 10)]
+mock.faker.seed(7)
+const second = [mock.faker.uuid(), mock.faker.name(), mock.faker.email(), mock.faker.int(1, 10)]
+JSON.stringify(first) == JSON.stringify(second)
+// !js
+`)
+
+	suite.True(value.ToBoolean())
+}
+
+func (suite *fakerSuite) TestRanges() {
+	value := suite.js(`
+// js
+let ok = true
+for (let i = 0; i < 50; i++) {
+  const n = mock.faker.int(10, 20)
+  const f = mock.faker.float(1.5, 2.5)
+  const d = mock.faker.date("2021-01-01", "2021-12-31")
+  ok = ok && n >= 10 && n <= 20 && f >= 1.5 && f <= 2.5 && d.startsWith("2021-")
+}
+ok = ok && ["a", "b"].includes(mock.faker.pick(["a", "b"]))
+ok = ok && mock.faker.pick([]) === undefined
+ok = ok && mock.faker.pick({ length: -1 }) === undefined
+ok = ok && typeof mock.faker.bool() == "boolean"
+ok = ok && mock.faker.words(3).split(" ").length == 3
+ok = ok && typeof mock.faker.address().city == "string"
+ok
+// !js
+`)
+
+	suite.True(value.ToBoolean())
+}
+
+func (suite *fakerSuite) TestInvalidDate() {
+	_, err := suite.run(`mock.faker.date("yesterday")`)
+
+	suite.Error(err)
+}
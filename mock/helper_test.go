@@ -5,12 +5,16 @@
 package mock
 
 import (
+	"net/http"
 	"testing"
 
 	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"go.k6.io/k6/js/modulestest"
+	"go.k6.io/k6/lib"
+	"go.k6.io/k6/metrics"
 )
 
 type testHelper struct {
@@ -22,11 +26,24 @@ type testHelper struct {
 func newHelper(t *testing.T) *testHelper {
 	t.Helper()
 
+	return newHelperEnv(t, nil)
+}
+
+// newHelperEnv returns a test helper with module instance created with given environment variables.
+func newHelperEnv(t *testing.T, env map[string]string) *testHelper {
+	t.Helper()
+
 	runtime := modulestest.NewRuntime(t)
 	vu := runtime.VU // nolint:varnamelen
 
 	assert.NoError(t, vu.Runtime().Set("__VU", 1))
 
+	vu.InitEnvField.LookupEnv = func(key string) (string, bool) {
+		value, found := env[key]
+
+		return value, found
+	}
+
 	root := New()
 
 	var module *Module
@@ -68,3 +85,43 @@ func (suite *suiteBase) js(script string) sobek.Value {
 
 	return value
 }
+
+func moveToVUContext(helper *testHelper) chan metrics.SampleContainer {
+	samples := make(chan metrics.SampleContainer, 1000)
+	registry := metrics.NewRegistry()
+
+	helper.runtime.MoveToVUContext(&lib.State{ // nolint:exhaustruct
+		Samples: samples,
+		Tags:    lib.NewVUStateTags(registry.RootTagSet().With("scenario", "default")),
+	})
+
+	return samples
+}
+
+// moveToHTTPContext moves the helper to VU context with state required by k6 http API
+// and exposes the http module as http global.
+func moveToHTTPContext(helper *testHelper) chan metrics.SampleContainer {
+	samples := make(chan metrics.SampleContainer, 1000)
+	registry := metrics.NewRegistry()
+
+	helper.runtime.MoveToVUContext(&lib.State{ // nolint:exhaustruct
+		Options:        lib.Options{SystemTags: &metrics.DefaultSystemTagSet}, // nolint:exhaustruct
+		Logger:         logrus.StandardLogger(),
+		Transport:      http.DefaultTransport.(*http.Transport).Clone(), // nolint:forcetypeassert
+		BufferPool:     lib.NewBufferPool(),
+		BuiltinMetrics: metrics.RegisterBuiltinMetrics(registry),
+		Samples:        samples,
+		Tags:           lib.NewVUStateTags(registry.RootTagSet()),
+	})
+
+	go func() {
+		for range samples { // nolint:revive
+		}
+	}()
+
+	exports := helper.module.Exports()
+
+	helper.vu.Runtime().Set("http", exports.Default) // nolint:errcheck
+
+	return samples
+}
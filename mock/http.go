@@ -5,8 +5,12 @@
 package mock
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/grafana/sobek"
 	"go.k6.io/k6/js/common"
+	"go.k6.io/k6/lib/netext/httpext"
 )
 
 // XXX: add batch function support
@@ -14,9 +18,19 @@ import (
 var (
 	urlFirstMethods  = []string{"get", "head", "post", "put", "patch", "options", "del"}
 	urlSecondMethods = []string{"request", "asyncRequest"}
+	bodylessMethods  = map[string]bool{"get": true, "head": true}
+	asyncMethods     = map[string]bool{"asyncRequest": true}
 )
 
+// wrapHTTPExports wraps the request functions of the http module. The http module returns the same exports
+// object on every call, it is wrapped only once, stacked wrappers would re-route bypassed requests.
 func (mod *Module) wrapHTTPExports(defaults *sobek.Object) {
+	if mod.wrapped {
+		return
+	}
+
+	mod.wrapped = true
+
 	for _, method := range urlFirstMethods {
 		mod.wrap(defaults, method, 0)
 	}
@@ -34,16 +48,48 @@ func (mod *Module) wrap(this *sobek.Object, method string, index int) {
 		mod.throwf("%s must be callable", errInvalidArg, method)
 	}
 
+	paramsIndex := index + 2 // nolint:gomnd
+	if bodylessMethods[method] {
+		paramsIndex = index + 1
+	}
+
 	wrapper := func(call sobek.FunctionCall) sobek.Value {
-		if len(call.Arguments) > index {
-			mod.rewrite(call.Arguments, index)
+		// arguments are backed by the VM's stack, so they are copied before rewriting
+		args := append([]sobek.Value{}, call.Arguments...)
+
+		if err := mod.configure(); err != nil {
+			mod.throw(err)
+		}
+
+		target := ""
+
+		if len(args) > index {
+			args, target = mod.route(args, index, paramsIndex)
 		}
 
-		v, err := callable(mod.runtime().GlobalObject(), call.Arguments...)
+		// the mock server decides by this whether middlewares can run while the request is pending
+		if !asyncMethods[method] {
+			mod.checkSyncCall(method, target)
+			mod.calls.enter()
+			defer mod.calls.exit()
+
+			// unmatched requests of earlier asynchronous calls are not failures of this call
+			mod.misses.take(target)
+		}
+
+		v, err := callable(mod.runtime().GlobalObject(), args...)
 		if err != nil {
 			common.Throw(mod.runtime(), err)
 		}
 
+		if asyncMethods[method] {
+			return v
+		}
+
+		if misses := mod.misses.take(target); len(misses) != 0 {
+			mod.throwf("%s", errUnmatched, strings.Join(misses, ", "))
+		}
+
 		return v
 	}
 
@@ -52,3 +98,180 @@ func (mod *Module) wrap(this *sobek.Object, method string, index int) {
 		common.Throw(mod.runtime(), err)
 	}
 }
+
+// mockParam returns the value of the mock field of request params.
+// The enabled flag is false for { mock: false }, name is the selected mock for { mock: 'name' }.
+func mockParam(args []sobek.Value, index int) (string, bool) {
+	if len(args) <= index {
+		return "", true
+	}
+
+	obj, ok := args[index].(*sobek.Object)
+	if !ok {
+		return "", true
+	}
+
+	v := obj.Get("mock")
+	if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return "", true
+	}
+
+	if v.ExportType().Kind() == reflect.String {
+		return v.String(), true
+	}
+
+	return "", v.ToBoolean()
+}
+
+// checkSyncCall throws if the target of a synchronous call is a mock in asynchronous mode.
+// Its handlers would be scheduled on the event loop, which is blocked by the call until the request timeout.
+func (mod *Module) checkSyncCall(method string, target string) {
+	if srv, found := mod.servers[target]; found && srv.mode == modeAsync {
+		mod.throwf("http.%s would block until timeout, mock %s is in asynchronous mode; "+
+			"use sync: true or remove sync: false from its options, or use http.asyncRequest",
+			errSyncCall, method, target)
+	}
+}
+
+// route rewrites the URL argument and the params argument of a request according to the matching
+// (or selected) mock and returns the target of the mock. The mock field of the params is removed.
+func (mod *Module) route(args []sobek.Value, index int, paramsIndex int) ([]sobek.Value, string) {
+	name, enabled := mockParam(args, paramsIndex)
+	original := args[index].String()
+
+	if _, inactive := mod.inactive[name]; inactive {
+		enabled = false
+	}
+
+	switch {
+	case !enabled:
+		header := ""
+
+		// mocks reached by the transport would catch the request without the header
+		if !mod.dialers.empty() {
+			header = mockHeaderBypass
+		}
+
+		return mod.params(args, paramsIndex, "", original, header), ""
+	case len(name) != 0:
+		target, dialed := mod.rewriteTo(args, index, name)
+		if dialed {
+			return mod.params(args, paramsIndex, target, original, target), target
+		}
+
+		return mod.params(args, paramsIndex, target, original, ""), target
+	}
+
+	target := mod.rewrite(args, index)
+
+	return mod.params(args, paramsIndex, target, original, ""), target
+}
+
+// params returns arguments with a copy of params argument, without the mock field.
+// If target is not empty, params are extended by tags referring to the mock and to the original URL.
+// If header is not empty, the mock selection header is added for the transport.
+// The params object passed by the caller is not modified.
+func (mod *Module) params(args []sobek.Value, index int, target string, original string, header string) []sobek.Value {
+	if _, hasMock := paramsField(args, index, "mock"); !hasMock && len(target) == 0 && len(header) == 0 {
+		return args
+	}
+
+	return mod.tagParams(args, index, target, original, header)
+}
+
+func paramsField(args []sobek.Value, index int, name string) (sobek.Value, bool) {
+	if len(args) <= index {
+		return nil, false
+	}
+
+	obj, ok := args[index].(*sobek.Object)
+	if !ok {
+		return nil, false
+	}
+
+	v := obj.Get(name)
+
+	return v, v != nil
+}
+
+// tagParams returns arguments with params extended by tags referring to the mock and to the original URL.
+// The params object passed by the caller is not modified.
+func (mod *Module) tagParams(
+	args []sobek.Value,
+	index int,
+	target string,
+	original string,
+	header string,
+) []sobek.Value {
+	runtime := mod.runtime()
+
+	for len(args) <= index {
+		args = append(args, sobek.Undefined())
+	}
+
+	params := runtime.NewObject()
+	tags := runtime.NewObject()
+	headers := runtime.NewObject()
+
+	mustSet := func(obj *sobek.Object, name string, value interface{}) {
+		if err := obj.Set(name, value); err != nil {
+			mod.throw(err)
+		}
+	}
+
+	copyProps := func(from sobek.Value, to *sobek.Object) {
+		if obj, ok := from.(*sobek.Object); ok {
+			for _, key := range obj.Keys() {
+				mustSet(to, key, obj.Get(key))
+			}
+		}
+	}
+
+	copyProps(args[index], params)
+
+	if err := params.Delete("mock"); err != nil {
+		mod.throw(err)
+	}
+
+	if len(header) != 0 {
+		if from, ok := args[index].(*sobek.Object); ok {
+			copyProps(from.Get("headers"), headers)
+		}
+
+		mustSet(headers, mockHeader, header)
+		mustSet(params, "headers", headers)
+		mod.installTransport()
+	}
+
+	if len(target) == 0 {
+		args[index] = params
+
+		return args
+	}
+
+	if from, ok := args[index].(*sobek.Object); ok {
+		copyProps(from.Get("tags"), tags)
+	}
+
+	if v := tags.Get("name"); v == nil || sobek.IsUndefined(v) {
+		mustSet(tags, "name", cleanURL(original))
+	}
+
+	mustSet(tags, "mocked", "true")
+	mustSet(tags, "mock_target", target)
+	mustSet(params, "tags", tags)
+
+	args[index] = params
+
+	return args
+}
+
+// cleanURL masks user info in the URL, like k6 does for the url and name tags.
+func cleanURL(loc string) string {
+	u, err := httpext.NewURL(loc, loc)
+	if err != nil {
+		return loc
+	}
+
+	return u.Clean()
+}
@@ -0,0 +1,715 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+// Supported JWS algorithms.
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+	algES256 = "ES256"
+
+	rsaKeyBits = 2048
+	es256Size  = 32
+)
+
+// jwtKey is a key for signing and verifying JSON Web Tokens.
+// HMAC keys have secret, asymmetric keys have public and optionally private key.
+type jwtKey struct {
+	id      string
+	alg     string
+	secret  []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+func newRSAKey(id string) (*jwtKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtKey{id: id, alg: algRS256, private: private, public: &private.PublicKey}, nil // nolint:exhaustruct
+}
+
+// newSignerKey returns the key of a private key, with algorithm chosen by key type.
+func newSignerKey(private crypto.Signer) (*jwtKey, error) {
+	key := &jwtKey{private: private, public: private.Public()} // nolint:exhaustruct
+
+	return key, key.detectAlg()
+}
+
+// newPublicKey returns the key of a public key, with algorithm chosen by key type.
+func newPublicKey(public crypto.PublicKey) (*jwtKey, error) {
+	key := &jwtKey{public: public} // nolint:exhaustruct
+
+	return key, key.detectAlg()
+}
+
+func (key *jwtKey) detectAlg() error {
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		key.alg = algRS256
+	case *ecdsa.PublicKey:
+		if public.Curve != elliptic.P256() {
+			return fmt.Errorf("%w: only P-256 elliptic curve keys are supported", errInvalidArg)
+		}
+
+		key.alg = algES256
+	default:
+		return fmt.Errorf("%w: unsupported key type %T", errInvalidArg, key.public)
+	}
+
+	return nil
+}
+
+// parsePEMKey parses a PEM encoded private or public key (PKCS #1, PKCS #8, SEC 1 or PKIX),
+// or the public key of a certificate.
+func parsePEMKey(data string) (*jwtKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("%w: invalid PEM data", errInvalidArg)
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			parsed = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM block type %s", errInvalidArg, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidArg, err.Error())
+	}
+
+	if signer, isSigner := parsed.(crypto.Signer); isSigner {
+		return newSignerKey(signer)
+	}
+
+	return newPublicKey(parsed)
+}
+
+// parseJWK parses a JSON Web Key: public RSA or P-256 EC key, or symmetric (oct) key.
+func parseJWK(jwk map[string]interface{}) (*jwtKey, error) {
+	field := func(name string) ([]byte, error) {
+		str, _ := jwk[name].(string)
+
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(str, "="))
+		if err != nil || len(data) == 0 {
+			return nil, fmt.Errorf("%w: invalid JWK parameter %s", errInvalidArg, name)
+		}
+
+		return data, nil
+	}
+
+	var (
+		key *jwtKey
+		err error
+	)
+
+	switch jwk["kty"] {
+	case "oct":
+		var secret []byte
+
+		if secret, err = field("k"); err == nil {
+			key = &jwtKey{alg: algHS256, secret: secret} // nolint:exhaustruct
+		}
+	case "RSA":
+		key, err = parseRSAJWK(field)
+	case "EC":
+		if jwk["crv"] != "P-256" {
+			return nil, fmt.Errorf("%w: unsupported JWK curve %v", errInvalidArg, jwk["crv"])
+		}
+
+		key, err = parseECJWK(field)
+	default:
+		return nil, fmt.Errorf("%w: unsupported JWK key type %v", errInvalidArg, jwk["kty"])
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key.id, _ = jwk["kid"].(string)
+
+	if alg, found := jwk["alg"].(string); found && alg != key.alg {
+		return nil, fmt.Errorf("%w: unsupported JWK algorithm %s", errInvalidArg, alg)
+	}
+
+	return key, nil
+}
+
+func parseRSAJWK(field func(string) ([]byte, error)) (*jwtKey, error) {
+	n, err := field("n")
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := field("e")
+	if err != nil {
+		return nil, err
+	}
+
+	return newPublicKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+}
+
+func parseECJWK(field func(string) ([]byte, error)) (*jwtKey, error) {
+	x, err := field("x")
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := field("y")
+	if err != nil {
+		return nil, err
+	}
+
+	public := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+	if !public.Curve.IsOnCurve(public.X, public.Y) { // nolint:staticcheck
+		return nil, fmt.Errorf("%w: invalid EC point", errInvalidArg)
+	}
+
+	return newPublicKey(public)
+}
+
+// jwk returns the public part of the key in JSON Web Key format.
+func (key *jwtKey) jwk() map[string]interface{} {
+	jwk := map[string]interface{}{"use": "sig", "alg": key.alg}
+
+	if len(key.id) != 0 {
+		jwk["kid"] = key.id
+	}
+
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk["kty"] = "EC"
+		jwk["crv"] = "P-256"
+		jwk["x"] = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, es256Size)))
+		jwk["y"] = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, es256Size)))
+	}
+
+	return jwk
+}
+
+// sign returns the signed compact serialization of the claims.
+func (key *jwtKey) sign(claims map[string]interface{}) (string, error) {
+	head := map[string]string{"alg": key.alg, "typ": "JWT"}
+
+	if len(key.id) != 0 {
+		head["kid"] = key.id
+	}
+
+	header, err := json.Marshal(head)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+
+	switch private := key.private.(type) {
+	case nil:
+		if key.alg != algHS256 {
+			return "", fmt.Errorf("%w: signing requires private key", errInvalidArg)
+		}
+
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write([]byte(signed)) // nolint:errcheck
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+
+		if r, s, err = ecdsa.Sign(rand.Reader, private, digest[:]); err == nil {
+			signature = append(r.FillBytes(make([]byte, es256Size)), s.FillBytes(make([]byte, es256Size))...)
+		}
+	default:
+		err = fmt.Errorf("%w: unsupported key type %T", errInvalidArg, key.private)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// verify checks the signature of the token.
+func (key *jwtKey) verify(token *jwtToken) error {
+	if token.header["alg"] != key.alg {
+		return fmt.Errorf("%w: unexpected algorithm %v", errInvalidToken, token.header["alg"])
+	}
+
+	if kid, found := token.header["kid"]; found && len(key.id) != 0 && kid != key.id {
+		return fmt.Errorf("%w: unknown key id %v", errInvalidToken, kid)
+	}
+
+	digest := sha256.Sum256([]byte(token.signed))
+	valid := false
+
+	switch public := key.public.(type) {
+	case nil:
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write([]byte(token.signed)) // nolint:errcheck
+		valid = hmac.Equal(mac.Sum(nil), token.signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(public, crypto.SHA256, digest[:], token.signature) == nil
+	case *ecdsa.PublicKey:
+		if len(token.signature) == 2*es256Size {
+			r := new(big.Int).SetBytes(token.signature[:es256Size])
+			s := new(big.Int).SetBytes(token.signature[es256Size:])
+			valid = ecdsa.Verify(public, digest[:], r, s)
+		}
+	}
+
+	if !valid {
+		return fmt.Errorf("%w: signature mismatch", errInvalidToken)
+	}
+
+	return nil
+}
+
+// jwtToken is a parsed (not yet verified) JSON Web Token.
+type jwtToken struct {
+	header    map[string]interface{}
+	claims    map[string]interface{}
+	payload   []byte
+	signed    string
+	signature []byte
+}
+
+func parseJWT(str string) (*jwtToken, error) {
+	parts := strings.Split(str, ".")
+	if len(parts) != 3 { // nolint:gomnd
+		return nil, fmt.Errorf("%w: malformed token", errInvalidToken)
+	}
+
+	token := &jwtToken{signed: parts[0] + "." + parts[1]} // nolint:exhaustruct
+
+	decode := func(part string, dest interface{}) ([]byte, error) {
+		data, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidToken, err.Error())
+		}
+
+		if dest == nil {
+			return data, nil
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(dest); err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidToken, err.Error())
+		}
+
+		return data, nil
+	}
+
+	var err error
+
+	if _, err = decode(parts[0], &token.header); err != nil {
+		return nil, err
+	}
+
+	if token.payload, err = decode(parts[1], &token.claims); err != nil {
+		return nil, err
+	}
+
+	if token.signature, err = decode(parts[2], nil); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// numericClaim returns the value of a NumericDate claim (e.g. exp).
+func (token *jwtToken) numericClaim(name string) (int64, bool) {
+	num, isNum := token.claims[name].(json.Number)
+	if !isNum {
+		return 0, false
+	}
+
+	value, err := num.Float64()
+	if err != nil {
+		return 0, false
+	}
+
+	return int64(value), true
+}
+
+// validate checks the time related claims of the token.
+func (token *jwtToken) validate(now time.Time) error {
+	if exp, found := token.numericClaim("exp"); found && now.Unix() >= exp {
+		return fmt.Errorf("%w: token expired", errInvalidToken)
+	}
+
+	if nbf, found := token.numericClaim("nbf"); found && now.Unix() < nbf {
+		return fmt.Errorf("%w: token not valid yet", errInvalidToken)
+	}
+
+	return nil
+}
+
+// scopes returns the scopes granted by the token (space separated scope claim).
+func (token *jwtToken) scopes() []string {
+	str, _ := token.claims["scope"].(string)
+
+	return strings.Fields(str)
+}
+
+// hasAudience reports whether the aud claim (string or array) contains given audience.
+func (token *jwtToken) hasAudience(audience string) bool {
+	switch aud := token.claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, item := range aud {
+			if item == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// jwtVerifier verifies tokens by a set of keys (e.g. a JWKS), and checks issuer, audience and time related claims.
+type jwtVerifier struct {
+	keys     []*jwtKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// verify parses and verifies the token. The key is selected by the kid header if present.
+func (verifier *jwtVerifier) verify(str string) (*jwtToken, error) {
+	token, err := parseJWT(str)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, hasKid := token.header["kid"]
+	err = fmt.Errorf("%w: no key for algorithm %v", errInvalidToken, token.header["alg"])
+
+	for _, key := range verifier.keys {
+		if key.alg != token.header["alg"] || (hasKid && len(key.id) != 0 && key.id != kid) {
+			continue
+		}
+
+		if err = key.verify(token); err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(verifier.issuer) != 0 && token.claims["iss"] != verifier.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %v", errInvalidToken, token.claims["iss"])
+	}
+
+	if len(verifier.audience) != 0 && !token.hasAudience(verifier.audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", errInvalidToken)
+	}
+
+	if err := token.validate(verifier.now()); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// keyStore holds the signing keys and the refresh tokens of the token issuers by target.
+// It is shared by VUs, so tokens issued in one VU (e.g. in setup) are valid in all VUs.
+type keyStore struct {
+	mu     sync.Mutex
+	keys   map[string]*jwtKey
+	grants map[string]*grantStore
+}
+
+func newKeyStore() *keyStore {
+	return &keyStore{keys: make(map[string]*jwtKey), grants: make(map[string]*grantStore)} // nolint:exhaustruct
+}
+
+// refreshTokens returns the refresh token store of given issuer.
+func (store *keyStore) refreshTokens(issuer string) *grantStore {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	grants, found := store.grants[issuer]
+	if !found {
+		grants = &grantStore{grants: make(map[string]*oauth2Grant)} // nolint:exhaustruct
+		store.grants[issuer] = grants
+	}
+
+	return grants
+}
+
+// get returns the key of given issuer, the key is generated on first use.
+func (store *keyStore) get(issuer string) (*jwtKey, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if key, found := store.keys[issuer]; found {
+		return key, nil
+	}
+
+	digest := sha256.Sum256([]byte(issuer))
+
+	key, err := newRSAKey(base64.RawURLEncoding.EncodeToString(digest[:8]))
+	if err != nil {
+		return nil, err
+	}
+
+	store.keys[issuer] = key
+
+	return key, nil
+}
+
+// jwtObject returns the mock.jwt namespace.
+func (mod *Module) jwtObject() *sobek.Object {
+	obj := mod.runtime().NewObject()
+
+	obj.Set("sign", mod.signJWT) // nolint:errcheck
+
+	return obj
+}
+
+// signJWT returns the claims signed by the key. The algorithm is chosen by the key:
+// HS256 for secrets, RS256 or ES256 for PEM encoded private keys and for OAuth2 provider mocks.
+func (mod *Module) signJWT(claims sobek.Value, value sobek.Value, options sobek.Value) string {
+	payload, isMap := claims.Export().(map[string]interface{})
+	if !isMap {
+		mod.throwf("claims must be an object", errInvalidArg)
+	}
+
+	keys := mod.jwtKeys(value)
+	if len(keys) != 1 {
+		mod.throwf("signing requires a single key", errInvalidArg)
+	}
+
+	key := keys[0]
+
+	if opts, isObj := options.(*sobek.Object); isObj {
+		if kid := mod.optString(opts, "kid", key.id); kid != key.id {
+			copied := *key
+			copied.id = kid
+			key = &copied
+		}
+	}
+
+	token, err := key.sign(payload)
+	if err != nil {
+		mod.throw(err)
+	}
+
+	return token
+}
+
+// jwtKeys returns the keys of a key value: secret, PEM encoded key, JWK, JWKS or OAuth2 provider mock.
+func (mod *Module) jwtKeys(value sobek.Value) []*jwtKey {
+	if value == nil || sobek.IsUndefined(value) || sobek.IsNull(value) {
+		mod.throwf("missing key", errInvalidArg)
+	}
+
+	if provider := mod.marked(value).provider; provider != nil {
+		return []*jwtKey{provider.key}
+	}
+
+	if value.ExportType().Kind() == reflect.String {
+		str := value.String()
+
+		if !strings.Contains(str, "-----BEGIN ") {
+			if len(str) == 0 {
+				mod.throwf("empty secret", errInvalidArg)
+			}
+
+			return []*jwtKey{{alg: algHS256, secret: []byte(str)}} // nolint:exhaustruct
+		}
+
+		key, err := parsePEMKey(str)
+		if err != nil {
+			mod.throw(err)
+		}
+
+		return []*jwtKey{key}
+	}
+
+	jwk, isMap := value.Export().(map[string]interface{})
+	if !isMap {
+		mod.throwf("key must be a secret, PEM encoded key, JWK, JWKS or OAuth2 provider", errInvalidArg)
+	}
+
+	items := []interface{}{jwk}
+
+	if list, isJWKS := jwk["keys"].([]interface{}); isJWKS {
+		items = list
+	}
+
+	keys := make([]*jwtKey, 0, len(items))
+
+	for _, item := range items {
+		jwk, isMap := item.(map[string]interface{})
+		if !isMap {
+			mod.throwf("JWKS keys must be objects", errInvalidArg)
+		}
+
+		key, err := parseJWK(jwk)
+		if err != nil {
+			mod.throw(err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// newJWTVerifier returns the verifier of the jwt mock option, or nil if there is no key to verify with.
+func (mod *Module) newJWTVerifier(opts *sobek.Object) *jwtVerifier {
+	if opts == nil {
+		return nil
+	}
+
+	verifier := &jwtVerifier{
+		issuer:   mod.optString(opts, "issuer", ""),
+		audience: mod.optString(opts, "audience", ""),
+		now:      time.Now,
+	}
+
+	for _, name := range []string{"key", "jwks"} {
+		if v := opts.Get(name); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+			verifier.keys = append(verifier.keys, mod.jwtKeys(v)...)
+		}
+	}
+
+	if len(verifier.keys) == 0 {
+		return nil
+	}
+
+	return verifier
+}
+
+// jwtMiddleware returns a middleware which defines the jwt property of the request: the claims of the bearer token.
+// The token is parsed on first access. If verifier is given, tokens failed to verify are ignored (jwt is null).
+func (mod *Module) jwtMiddleware(verifier *jwtVerifier) sobek.Value {
+	runtime := mod.runtime()
+
+	return runtime.ToValue(func(call sobek.FunctionCall) sobek.Value {
+		req := call.Argument(0).ToObject(runtime)
+
+		var claims sobek.Value
+
+		getter := runtime.ToValue(func() sobek.Value {
+			if claims == nil {
+				claims = mod.requestClaims(req, verifier)
+			}
+
+			return claims
+		})
+
+		if err := req.DefineAccessorProperty("jwt", getter, nil, sobek.FLAG_FALSE, sobek.FLAG_TRUE); err != nil {
+			mod.throw(err)
+		}
+
+		if next, ok := sobek.AssertFunction(call.Argument(2)); ok {
+			if _, err := next(sobek.Undefined()); err != nil {
+				mod.throw(err)
+			}
+		}
+
+		return sobek.Undefined()
+	})
+}
+
+func (mod *Module) requestClaims(req *sobek.Object, verifier *jwtVerifier) sobek.Value {
+	str, found := parseBearer(mod.invoke(req, "get", "Authorization").String())
+	if !found {
+		return sobek.Null()
+	}
+
+	var (
+		token *jwtToken
+		err   error
+	)
+
+	if verifier != nil {
+		token, err = verifier.verify(str)
+	} else {
+		token, err = parseJWT(str)
+	}
+
+	if err != nil {
+		mod.logger.WithError(err).WithField("path", req.Get("path").String()).Debug("bearer token ignored")
+
+		return sobek.Null()
+	}
+
+	var claims map[string]interface{}
+
+	if err := json.Unmarshal(token.payload, &claims); err != nil {
+		return sobek.Null()
+	}
+
+	return mod.runtime().ToValue(claims)
+}
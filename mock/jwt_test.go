@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWTKey(t *testing.T) {
+	t.Parallel()
+
+	key, err := newRSAKey("k1")
+
+	assert.NoError(t, err)
+
+	now := time.Now()
+
+	str, err := key.sign(map[string]interface{}{
+		"sub":   "alice",
+		"aud":   []string{"api", "web"},
+		"scope": "read write",
+		"exp":   now.Add(time.Minute).Unix(),
+	})
+
+	assert.NoError(t, err)
+
+	token, err := parseJWT(str)
+
+	assert.NoError(t, err)
+	assert.NoError(t, key.verify(token))
+	assert.NoError(t, token.validate(now))
+	assert.ErrorIs(t, token.validate(now.Add(time.Hour)), errInvalidToken)
+	assert.Equal(t, "alice", token.claims["sub"])
+	assert.Equal(t, []string{"read", "write"}, token.scopes())
+	assert.True(t, token.hasAudience("web"))
+	assert.False(t, token.hasAudience("admin"))
+	assert.Equal(t, "RSA", key.jwk()["kty"])
+	assert.Equal(t, "AQAB", key.jwk()["e"])
+
+	other, err := newRSAKey("k1")
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, other.verify(token), errInvalidToken)
+
+	parts := strings.Split(str, ".")
+	tampered, err := parseJWT(parts[0] + "." + parts[0] + "." + parts[2])
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, key.verify(tampered), errInvalidToken)
+
+	for _, malformed := range []string{"", "a.b", "a.b.c", parts[0] + ".!." + parts[2]} {
+		_, err = parseJWT(malformed)
+
+		assert.ErrorIs(t, err, errInvalidToken, malformed)
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	t.Parallel()
+
+	store := newKeyStore()
+
+	key, err := store.get("https://idp.example.com")
+
+	assert.NoError(t, err)
+
+	same, err := store.get("https://idp.example.com")
+
+	assert.NoError(t, err)
+	assert.Same(t, key, same)
+
+	// refresh tokens issued by the provider of one VU can be redeemed in other VUs
+	grants := store.refreshTokens("https://idp.example.com")
+
+	assert.Same(t, grants, store.refreshTokens("https://idp.example.com"))
+	assert.NotSame(t, grants, store.refreshTokens("https://idp.example.net"))
+
+	grants.add("r1", &oauth2Grant{client: "app", user: "alice", scopes: nil})
+
+	redeem := func(grant *oauth2Grant) (*oauth2Grant, error) {
+		if grant == nil {
+			return nil, errInvalidToken
+		}
+
+		return grant, nil
+	}
+
+	grant, err := store.refreshTokens("https://idp.example.com").use("r1", redeem)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", grant.user)
+
+	_, err = grants.use("r1", redeem)
+
+	assert.ErrorIs(t, err, errInvalidToken)
+}
+
+func TestJWTAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+
+	assert.NoError(t, err)
+
+	ecKey, err := parsePEMKey(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+
+	assert.NoError(t, err)
+	assert.Equal(t, algES256, ecKey.alg)
+
+	rsaKey, err := newRSAKey("")
+
+	assert.NoError(t, err)
+
+	hsKey := &jwtKey{alg: algHS256, secret: []byte("secret")} // nolint:exhaustruct
+
+	for _, key := range []*jwtKey{hsKey, rsaKey, ecKey} {
+		str, err := key.sign(map[string]interface{}{"sub": "alice"})
+
+		assert.NoError(t, err, key.alg)
+
+		token, err := parseJWT(str)
+
+		assert.NoError(t, err, key.alg)
+		assert.Equal(t, key.alg, token.header["alg"])
+		assert.NoError(t, key.verify(token), key.alg)
+
+		if key.public == nil {
+			continue
+		}
+
+		public, err := parseJWK(key.jwk())
+
+		assert.NoError(t, err, key.alg)
+		assert.NoError(t, public.verify(token), key.alg)
+
+		_, err = public.sign(map[string]interface{}{})
+
+		assert.ErrorIs(t, err, errInvalidArg, key.alg)
+	}
+
+	// the RSA public key must not be usable as HMAC secret
+	str, err := hsKey.sign(map[string]interface{}{})
+
+	assert.NoError(t, err)
+
+	token, err := parseJWT(str)
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, rsaKey.verify(token), errInvalidToken)
+
+	_, err = parseJWK(map[string]interface{}{"kty": "EC", "crv": "P-384"})
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	_, err = parsePEMKey("secret")
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	t.Parallel()
+
+	key := &jwtKey{id: "k1", alg: algHS256, secret: []byte("secret")}  // nolint:exhaustruct
+	other := &jwtKey{id: "k2", alg: algHS256, secret: []byte("other")} // nolint:exhaustruct
+
+	verifier := &jwtVerifier{keys: []*jwtKey{other, key}, issuer: "https://idp", audience: "api", now: time.Now}
+
+	sign := func(key *jwtKey, claims map[string]interface{}) string {
+		str, err := key.sign(claims)
+
+		assert.NoError(t, err)
+
+		return str
+	}
+
+	valid := map[string]interface{}{"iss": "https://idp", "aud": "api", "exp": time.Now().Add(time.Minute).Unix()}
+
+	_, err := verifier.verify(sign(key, valid))
+
+	assert.NoError(t, err)
+
+	_, err = verifier.verify(sign(&jwtKey{id: "k3", alg: algHS256, secret: []byte("secret")}, valid)) // nolint:exhaustruct
+
+	assert.ErrorIs(t, err, errInvalidToken)
+
+	_, err = verifier.verify(sign(key, map[string]interface{}{"iss": "https://other", "aud": "api"}))
+
+	assert.ErrorIs(t, err, errInvalidToken)
+
+	_, err = verifier.verify(sign(key, map[string]interface{}{"iss": "https://idp", "aud": "web"}))
+
+	assert.ErrorIs(t, err, errInvalidToken)
+
+	_, err = verifier.verify(sign(key, map[string]interface{}{"iss": "https://idp", "aud": "api", "exp": 1}))
+
+	assert.ErrorIs(t, err, errInvalidToken)
+}
+
+func TestModuleJWT(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+const handler = (req, res) => res.json(req.jwt ? { sub: req.jwt.sub, admin: req.jwt.scope === "admin" } : null)
+
+mock("https://verified.example.com", app => {
+  app.get("/me", handler)
+}, { jwt: { key: "secret", issuer: "https://idp" } })
+
+mock("https://parsed.example.com", app => {
+  app.get("/me", handler)
+})
+
+const idp = mock.oauth2("https://idp.example.com")
+
+mock("https://provider.example.com", app => {
+  app.get("/me", handler)
+}, { jwt: { jwks: idp } })
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+const me = (host, token) => http.get(host + "/me", token ? { headers: { Authorization: "Bearer " + token } } : {}).json()
+
+const exp = Math.floor(Date.now() / 1000) + 60
+const valid = mock.jwt.sign({ iss: "https://idp", sub: "alice", scope: "admin", exp }, "secret")
+const forged = mock.jwt.sign({ iss: "https://idp", sub: "mallory", exp }, "guess")
+
+;[
+  me("https://verified.example.com", valid),
+  me("https://verified.example.com", forged),
+  me("https://verified.example.com"),
+  me("https://parsed.example.com", forged),
+  me("https://parsed.example.com", "malformed"),
+  me("https://provider.example.com", mock.jwt.sign({ sub: "bob" }, idp)),
+  me("https://provider.example.com", valid),
+]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"sub": "alice", "admin": true},
+		nil,
+		nil,
+		map[string]interface{}{"sub": "mallory", "admin": false},
+		nil,
+		map[string]interface{}{"sub": "bob", "admin": false},
+		nil,
+	}, value.Export())
+
+	for _, script := range []string{
+		`mock.jwt.sign({}, "")`,
+		`mock.jwt.sign("claims", "secret")`,
+		`mock.jwt.sign({}, { kty: "RSA", n: "AQAB", e: "AQAB" })`,
+		`mock.jwt.sign({}, 42)`,
+	} {
+		_, err = helper.vu.Runtime().RunString(script)
+
+		assert.ErrorIs(t, err, errInvalidArg, script)
+	}
+}
@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/errext"
+	"go.k6.io/k6/errext/exitcodes"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/execution"
+)
+
+// Scopes of mock definitions.
+const (
+	scopeVU        = "vu"
+	scopeIteration = "iteration"
+)
+
+// reset removes all mock definitions of the VU.
+func (mod *Module) reset() {
+	for _, target := range mod.targets() {
+		if err := mod.remove(target); err != nil {
+			mod.throw(err)
+		}
+	}
+}
+
+// list returns the targets of the mock definitions of the VU (including not yet started ones in VU 0).
+func (mod *Module) list() sobek.Value {
+	return mod.runtime().ToValue(mod.targets())
+}
+
+func (mod *Module) targets() []string {
+	targets := make([]string, 0, len(mod.apps)+len(mod.pending))
+
+	for target := range mod.apps {
+		targets = append(targets, target)
+	}
+
+	for target := range mod.pending {
+		targets = append(targets, target)
+	}
+
+	sort.Strings(targets)
+
+	return targets
+}
+
+// enable resumes mocking of the mock definition given by name (or target).
+func (mod *Module) enable(name string) {
+	mod.toggle(name, false)
+}
+
+// disable suspends mocking of the mock definition given by name (or target).
+// The mock server keeps running, requests are sent to the real host until the mock is enabled again.
+func (mod *Module) disable(name string) {
+	mod.toggle(name, true)
+}
+
+func (mod *Module) toggle(name string, disabled bool) {
+	// mock definitions switched off by K6_MOCK environment variable are left switched off
+	if _, inactive := mod.inactive[name]; inactive {
+		return
+	}
+
+	target, found := mod.resolve(name)
+	if !found {
+		mod.throwf("no such mock: %s", errInvalidArg, name)
+	}
+
+	if disabled {
+		mod.disabled[target] = true
+	} else {
+		delete(mod.disabled, target)
+	}
+
+	mod.dialers.disable(target, disabled)
+}
+
+// resolve returns the target of the mock definition given by name or target.
+func (mod *Module) resolve(name string) (string, bool) {
+	if target, found := mod.names[name]; found {
+		return target, true
+	}
+
+	if _, found := mod.apps[name]; found {
+		return name, true
+	}
+
+	_, found := mod.pending[name]
+
+	return name, found
+}
+
+// awaitStart registers a mock definition of the init context to get the defaults of options.ext.mock
+// when the VU starts its first iteration. The k6 options are not available to extensions in the init context.
+func (mod *Module) awaitStart(args *mockArgs) {
+	mod.initDefs[args.target] = args
+
+	events := mod.vu.Events().Local
+	if mod.iterStart != nil || events == nil {
+		return
+	}
+
+	sid, ch := events.Subscribe(event.IterStart)
+
+	mod.iterStart = func() { events.Unsubscribe(sid) }
+
+	go func() {
+		for evt := range ch {
+			// The VU waits for the event to be processed, so it is safe to touch the VU's mocks here.
+			// Applying the defaults does not use the runtime.
+			if err := mod.configure(); err != nil {
+				mod.abort(err)
+			}
+
+			if evt.Done != nil {
+				evt.Done()
+			}
+		}
+	}()
+}
+
+// applyDefaults applies the defaults of options.ext.mock to the mock definitions started in the init context.
+// The definition callbacks are not called again, servers with changed settings are restarted with the same
+// application. The iteration scope is not applied, mocks of the init context live as long as the VU.
+// It does not use the runtime.
+func (mod *Module) applyDefaults(vu int64) error {
+	defs := mod.initDefs
+	mod.initDefs = make(map[string]*mockArgs)
+
+	targets := make([]string, 0, len(defs))
+	for target := range defs {
+		targets = append(targets, target)
+	}
+
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		old, found := mod.servers[target]
+		if !found {
+			continue
+		}
+
+		args := defs[target]
+		opts := args.options.clone()
+
+		mod.config.apply(opts)
+		opts.scope = args.options.scope
+
+		args.settings.set(opts)
+		old.mode = opts.mode()
+
+		if opts.diagnose == old.diagnose && opts.strict == old.strict && opts.delay == old.delay &&
+			opts.proto == old.proto && opts.host == args.options.host && opts.basePort == args.options.basePort {
+			continue
+		}
+
+		srv := newServer(target, old.backend, old.routes, mod.logger)
+		srv.observe, srv.stop = old.observe, old.stop
+
+		old.stop = nil
+
+		if err := old.shutdown(); err != nil {
+			mod.logger.WithError(err).WithField("target", target).Debug("unable to stop mock server gracefully")
+		}
+
+		delete(mod.lookup, target)
+		mod.dialers.remove(target)
+
+		mod.servers[target] = srv
+
+		if err := mod.serve(srv, opts, vu); err != nil {
+			if rerr := mod.remove(target); rerr != nil {
+				mod.logger.WithError(rerr).WithField("target", target).Debug("unable to remove mock")
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// abort aborts the test run with the error, like test.abort() of k6/execution does.
+// The error is logged if there is no test run to abort.
+func (mod *Module) abort(err error) {
+	err = errext.WithExitCodeIfNone(err, exitcodes.InvalidConfig)
+
+	if !execution.AbortTestRun(mod.vu.Context(), err) {
+		mod.logger.WithError(err).Error("unable to apply mock options")
+	}
+}
+
+// scope registers an iteration scoped mock definition, which will be removed at the end of the iteration.
+func (mod *Module) scope(target string) {
+	mod.scoped[target] = struct{}{}
+
+	events := mod.vu.Events().Local
+	if mod.iterEnd != nil || events == nil {
+		return
+	}
+
+	sid, ch := events.Subscribe(event.IterEnd)
+
+	mod.iterEnd = func() { events.Unsubscribe(sid) }
+
+	go func() {
+		for evt := range ch {
+			// The VU waits for the event to be processed, so it is safe to touch the VU's mocks here.
+			// Removing mocks does not use the runtime.
+			for target := range mod.scoped {
+				if err := mod.remove(target); err != nil {
+					mod.logger.WithError(err).WithField("target", target).Warn("unable to remove iteration scoped mock")
+				}
+			}
+
+			if evt.Done != nil {
+				evt.Done()
+			}
+		}
+	}()
+}
+
+// teardown gracefully shuts down all mock servers of the VU.
+// It is called at the end of the test, when the VU is not running anymore; it does not use the runtime.
+func (mod *Module) teardown() {
+	if mod.iterStart != nil {
+		mod.iterStart()
+	}
+
+	if mod.iterEnd != nil {
+		mod.iterEnd()
+	}
+
+	for _, target := range mod.targets() {
+		if err := mod.remove(target); err != nil {
+			mod.logger.WithError(err).WithField("target", target).Debug("unable to remove mock")
+		}
+	}
+
+	// objects created outside of mock definitions have no mock to be removed with
+	mod.releaseMarkers("")
+
+	if mod.browser != nil {
+		mod.browser.CloseIdleConnections()
+	}
+}
+
+// moduleList holds the module instances of the VUs having running mock servers.
+// Module instances are added when their first mock server starts and released when their last one stops.
+type moduleList struct {
+	mu      sync.Mutex
+	modules map[*Module]struct{}
+}
+
+func (list *moduleList) add(mod *Module) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	if list.modules == nil {
+		list.modules = make(map[*Module]struct{})
+	}
+
+	list.modules[mod] = struct{}{}
+}
+
+func (list *moduleList) remove(mod *Module) {
+	list.mu.Lock()
+	defer list.mu.Unlock()
+
+	delete(list.modules, mod)
+}
+
+func (list *moduleList) teardown() {
+	list.mu.Lock()
+
+	all := list.modules
+	list.modules = nil
+
+	list.mu.Unlock()
+
+	for mod := range all {
+		mod.teardown()
+	}
+}
@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/imroc/req/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/common"
+)
+
+func TestModuleResetList(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	value, err := helper.vu.Runtime().RunString(`
+mock("https://b.example.com", app => {}, { sync: true })
+mock("https://a.example.com", app => {}, { sync: true })
+mock("https://a.example.com", app => {}, { sync: true })
+mock.list()
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, value.Export())
+	assert.Len(t, helper.module.servers, 2)
+
+	url := helper.module.lookup["https://a.example.com"]
+
+	value, err = helper.vu.Runtime().RunString(`
+mock.reset()
+mock.list()
+`)
+
+	assert.NoError(t, err)
+	assert.Empty(t, value.Export())
+	assert.Empty(t, helper.module.servers)
+	assert.Empty(t, helper.module.lookup)
+
+	_, err = req.Get(url)
+
+	assert.Error(t, err)
+}
+
+func TestModuleScope(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	local := event.NewEventSystem(10, logrus.StandardLogger())
+
+	helper.vu.EventsField = common.Events{Global: event.NewEventSystem(10, logrus.StandardLogger()), Local: local}
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://init.example.com", app => {}, { sync: true, scope: "iteration" })
+`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	_, err = helper.vu.Runtime().RunString(`
+mock("https://invalid.example.com", app => {}, { sync: true, scope: "test" })
+`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	moveToVUContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+mock("https://vu.example.com", app => {}, { sync: true, scope: "vu" })
+mock("https://iteration.example.com", app => {}, { sync: true, scope: "iteration" })
+mock.list()
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://iteration.example.com", "https://vu.example.com"}, value.Export())
+
+	wait := local.Emit(&event.Event{Type: event.IterEnd}) // nolint:exhaustruct
+	assert.NoError(t, wait(context.Background()))
+
+	assert.Equal(t, []string{"https://vu.example.com"}, helper.module.targets())
+	assert.Empty(t, helper.module.scoped)
+
+	helper.module.teardown()
+
+	assert.Empty(t, helper.module.targets())
+}
+
+func TestModuleListTeardown(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`mock("https://example.com", app => {}, { sync: true })`)
+
+	assert.NoError(t, err)
+
+	list := helper.module.running
+
+	assert.Contains(t, list.modules, helper.module)
+
+	list.teardown()
+
+	assert.Empty(t, helper.module.targets())
+	assert.Empty(t, list.modules)
+}
+
+func TestModuleListRelease(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	list := helper.module.running
+
+	// module instances without mock servers (e.g. the ones used for parsing options) are not kept
+	assert.Empty(t, list.modules)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://a.example.com", app => {}, { sync: true })
+mock("https://b.example.com", app => {}, { sync: true })
+`)
+
+	assert.NoError(t, err)
+	assert.Contains(t, list.modules, helper.module)
+
+	_, err = helper.vu.Runtime().RunString(`unmock("https://a.example.com")`)
+
+	assert.NoError(t, err)
+	assert.Contains(t, list.modules, helper.module)
+
+	_, err = helper.vu.Runtime().RunString(`unmock("https://b.example.com")`)
+
+	assert.NoError(t, err)
+	assert.Empty(t, list.modules)
+}
+
+func TestModuleEnableDisable(t *testing.T) {
+	t.Parallel()
+
+	real := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real")) // nolint:errcheck
+	}))
+
+	defer real.Close()
+
+	helper := newHelper(t)
+
+	assert.NoError(t, helper.vu.Runtime().Set("real", real.URL))
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { sync: true, name: "api" })
+
+mock(real, app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { sync: true, memory: true, name: "local" })
+`)
+
+	assert.NoError(t, err)
+
+	_, err = helper.vu.Runtime().RunString(`mock("https://example.net", app => {}, { sync: true, name: "api" })`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	_, err = helper.vu.Runtime().RunString(`mock.disable("missing")`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	rewrite := func() string {
+		args := []sobek.Value{helper.vu.Runtime().ToValue("https://example.com/")}
+
+		helper.module.rewrite(args, 0)
+
+		return args[0].String()
+	}
+
+	assert.NotEqual(t, "https://example.com/", rewrite())
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+const bodies = () => [http.get(real + "/").body, http.get(real + "/", { mock: "local" }).body]
+
+const result = [bodies()]
+
+mock.disable("local")
+result.push(bodies())
+
+mock.enable(real)
+result.push(bodies())
+
+result
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		[]interface{}{"mock", "mock"},
+		[]interface{}{"real", "mock"},
+		[]interface{}{"mock", "mock"},
+	}, value.Export())
+
+	_, err = helper.vu.Runtime().RunString(`mock.disable("api")`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/", rewrite())
+	assert.Contains(t, helper.module.servers, "https://example.com")
+
+	_, err = helper.vu.Runtime().RunString(`mock.enable("api")`)
+
+	assert.NoError(t, err)
+	assert.NotEqual(t, "https://example.com/", rewrite())
+
+	_, err = helper.vu.Runtime().RunString(`unmock("api")`)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, helper.module.servers, "https://example.com")
+	assert.NotContains(t, helper.module.names, "api")
+}
+
+func TestModuleDisablePending(t *testing.T) {
+	t.Parallel()
+
+	real := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real")) // nolint:errcheck
+	}))
+
+	defer real.Close()
+
+	helper := newHelper(t)
+
+	assert.NoError(t, helper.vu.Runtime().Set("__VU", 0))
+	assert.NoError(t, helper.vu.Runtime().Set("real", real.URL))
+
+	_, err := helper.vu.Runtime().RunString(`
+mock(real, app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { sync: true, memory: true, name: "local" })
+
+mock.disable("local")
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`[http.get(real + "/", { mock: "local" }).body, http.get(real + "/").body]`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"mock", "real"}, value.Export())
+}
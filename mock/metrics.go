@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"strconv"
+	"time"
+
+	"go.k6.io/k6/js/modules"
+	"go.k6.io/k6/metrics"
+)
+
+type mockMetrics struct {
+	requests          *metrics.Metric
+	unmatchedRequests *metrics.Metric
+	handlerDuration   *metrics.Metric
+	schemaViolations  *metrics.Metric
+	authFailures      *metrics.Metric
+}
+
+func newMetrics(vu modules.VU) *mockMetrics { // nolint:varnamelen
+	if vu.InitEnv() == nil || vu.InitEnv().Registry == nil {
+		return new(mockMetrics)
+	}
+
+	registry := vu.InitEnv().Registry
+
+	return &mockMetrics{
+		requests:          registry.MustNewMetric("mock_requests", metrics.Counter),
+		unmatchedRequests: registry.MustNewMetric("mock_unmatched_requests", metrics.Counter),
+		handlerDuration:   registry.MustNewMetric("mock_handler_duration", metrics.Trend, metrics.Time),
+		schemaViolations:  registry.MustNewMetric("mock_schema_violations", metrics.Counter),
+		authFailures:      registry.MustNewMetric("mock_auth_failures", metrics.Counter),
+	}
+}
+
+// observe emits metrics of a request served by a mock server.
+func (mod *Module) observe(exc *exchange) {
+	tags := map[string]string{
+		"target": exc.target,
+		"method": exc.method,
+		"status": strconv.Itoa(exc.status),
+	}
+
+	if exc.route != nil {
+		tags["route"] = exc.route.path
+		mod.coverageLog.hit(exc)
+	} else if !exc.answered {
+		mod.push(mod.metrics.unmatchedRequests, 1, tags)
+		mod.unmatched.add(exc)
+	}
+
+	if len(exc.auth) != 0 {
+		mod.push(mod.metrics.authFailures, 1, map[string]string{
+			"target": exc.target,
+			"method": exc.method,
+			"status": tags["status"],
+			"route":  tags["route"],
+			"scheme": exc.auth,
+		})
+	}
+
+	mod.push(mod.metrics.requests, 1, tags)
+	mod.push(mod.metrics.handlerDuration, metrics.D(exc.duration), tags)
+
+	if err := mod.recorder.record(exc); err != nil {
+		mod.logger.WithError(err).Warn("unable to record mock request")
+	}
+}
+
+// push emits a metric sample with VU tags extended by given tags.
+// It is a no-op outside of the VU context (no state available).
+func (mod *Module) push(metric *metrics.Metric, value float64, tags map[string]string) {
+	state := mod.vu.State()
+	if metric == nil || state == nil || state.Samples == nil {
+		return
+	}
+
+	ctm := state.Tags.GetCurrentValues()
+	set := ctm.Tags
+
+	for name, value := range tags {
+		if len(value) != 0 {
+			set = set.With(name, value)
+		}
+	}
+
+	metrics.PushIfNotDone(mod.vu.Context(), state.Samples, metrics.Sample{
+		TimeSeries: metrics.TimeSeries{
+			Metric: metric,
+			Tags:   set,
+		},
+		Time:     time.Now(),
+		Value:    value,
+		Metadata: ctm.Metadata,
+	})
+}
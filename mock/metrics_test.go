@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/js/modulestest"
+	"go.k6.io/k6/metrics"
+)
+
+func TestNewMetrics(t *testing.T) {
+	t.Parallel()
+
+	vu := modulestest.NewRuntime(t).VU // nolint:varnamelen
+
+	all := newMetrics(vu)
+
+	assert.NotNil(t, all.schemaViolations)
+	assert.Equal(t, all.schemaViolations, vu.InitEnv().Registry.Get("mock_schema_violations"))
+
+	vu.InitEnvField = nil
+
+	assert.Nil(t, newMetrics(vu).schemaViolations)
+}
+
+func TestPush(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	metric := helper.module.metrics.schemaViolations
+
+	assert.NotPanics(t, func() { helper.module.push(metric, 1, nil) })
+
+	samples := moveToVUContext(helper)
+
+	helper.module.push(metric, 2, map[string]string{"target": "https://example.com", "empty": ""})
+
+	assert.Len(t, samples, 1)
+
+	sample := (<-samples).(metrics.Sample) // nolint:forcetypeassert
+
+	assert.Equal(t, metric, sample.Metric)
+	assert.Equal(t, 2.0, sample.Value)
+
+	tags := sample.Tags.Map()
+
+	assert.Equal(t, "https://example.com", tags["target"])
+	assert.Equal(t, "default", tags["scenario"])
+	assert.NotContains(t, tags, "empty")
+}
+
+func TestObserve(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	samples := moveToVUContext(helper)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://metrics.example.com", app => {
+  app.get("/users/:id", (req, res) => res.json({ id: req.params.id }))
+}, { sync: true })
+`)
+
+	assert.NoError(t, err)
+
+	base := helper.module.lookup["https://metrics.example.com"]
+
+	_, err = req.Get(base + "/users/1")
+	assert.NoError(t, err)
+
+	_, err = req.Get(base + "/orders")
+	assert.NoError(t, err)
+
+	counts := map[string]int{}
+	routes := map[string]string{}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case container := <-samples:
+			sample := container.(metrics.Sample) // nolint:forcetypeassert
+
+			counts[sample.Metric.Name]++
+
+			tags := sample.Tags.Map()
+
+			assert.Equal(t, "https://metrics.example.com", tags["target"])
+			assert.Equal(t, "GET", tags["method"])
+
+			if sample.Metric.Name == "mock_requests" {
+				routes[tags["status"]] = tags["route"]
+			}
+		case <-time.After(time.Second):
+			assert.Fail(t, "missing samples")
+		}
+	}
+
+	assert.Equal(t, map[string]int{"mock_requests": 2, "mock_unmatched_requests": 1, "mock_handler_duration": 2}, counts)
+	assert.Equal(t, map[string]string{"200": "/users/:id", "404": ""}, routes)
+}
@@ -5,24 +5,35 @@
 package mock
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"syscall"
 
 	"github.com/grafana/sobek"
+	"github.com/szkiba/xk6-mock/internal/muxpress"
 )
 
-func (mod *Module) skipMock() bool {
-	if v := mod.runtime().Get("__VU"); v == nil || v.ToInteger() == 0 {
-		return true
-	}
+// backendHost is the default address where mock servers are listening on.
+const backendHost = "127.0.0.1"
 
-	return false
+func (mod *Module) skipMock() bool {
+	return mod.vuNumber() == 0
 }
 
 type mockArgs struct {
 	target   string
 	callback sobek.Callable
 	options  *options
+	handler  http.Handler
+	routes   func(*routeTable)
+	settings *appSettings
 }
 
 func (mod *Module) newMockArgs(call sobek.FunctionCall) *mockArgs {
@@ -62,83 +73,426 @@ func (mod *Module) newMockArgs(call sobek.FunctionCall) *mockArgs {
 }
 
 func (mod *Module) mock(call sobek.FunctionCall) sobek.Value {
+	mod.register(mod.newMockArgs(call))
+
+	return sobek.Undefined()
+}
+
+// register defines the mock, or makes it pending until first use in VU 0.
+func (mod *Module) register(args *mockArgs) {
+	if args.options.skip {
+		return
+	}
+
+	name := args.options.name
+	if len(name) == 0 {
+		name = args.target
+	}
+
+	// mock definitions not selected by K6_MOCK environment variable are skipped,
+	// requests selecting them by name are sent to the real host
+	if !mod.active(name, args.target) {
+		mod.inactive[name] = args.target
+
+		return
+	}
+
+	if target, found := mod.names[name]; found && target != args.target {
+		mod.throwf("mock name %s is already used by %s", errInvalidArg, name, target)
+	}
+
+	// the new definition replaces the previous one
+	if err := mod.remove(args.target); err != nil {
+		mod.throw(err)
+	}
+
+	// VU 0 is used for parsing options and for running setup, teardown and handleSummary.
+	// The mock definition is started on first use, so parsing options does not start servers.
 	if mod.skipMock() {
-		return sobek.Undefined()
+		mod.pending[args.target] = args
+	} else {
+		mod.define(args)
 	}
 
-	args := mod.newMockArgs(call)
+	mod.names[name] = args.target
+}
 
-	if args.options.skip {
-		return sobek.Undefined()
+// define starts the mock server of the mock definition. The defaults of options.ext.mock are applied in VU context,
+// the definitions of the init context get them when the VU starts.
+func (mod *Module) define(args *mockArgs) {
+	if err := mod.configure(); err != nil {
+		mod.throw(err)
 	}
 
-	app, listen := mod.newApplication(args.options.sync)
+	if mod.config != nil {
+		mod.config.apply(args.options)
+	}
 
-	_, err := args.callback(mod.runtime().GlobalObject(), app)
-	if err != nil {
+	switch args.options.scope {
+	case "", scopeVU:
+	case scopeIteration:
+		if mod.vu.State() == nil {
+			mod.throwf("iteration scoped mock cannot be defined in init context: %s", errInvalidArg, args.target)
+		}
+	default:
+		mod.throwf("scope must be %q or %q for %s", errInvalidArg, scopeVU, scopeIteration, args.target)
+	}
+
+	if args.options.delay < 0 {
+		mod.throwf("delay cannot be negative for %s", errInvalidArg, args.target)
+	}
+
+	// the defaults are validated by parseConfig
+	if _, given := args.options.given["http2"]; given && !validProto(args.options.proto) {
+		mod.throwf("http2 must be true, %q or %q for %s", errInvalidArg, protoH2, protoH2C, args.target)
+	}
+
+	if args.options.tls {
+		proto, ok := tlsProto(args.options.proto)
+		if !ok {
+			mod.throwf("tls cannot be used with h2c for %s", errInvalidArg, args.target)
+		}
+
+		args.options.proto = proto
+	}
+
+	args.settings = newAppSettings(args.options)
+
+	app, handler, routes := mod.newBackend(args)
+
+	srv := newServer(args.target, handler, routes, mod.logger)
+	srv.observe = mod.observe
+	srv.stop = handler.Shutdown
+
+	if err := mod.serve(srv, args.options, mod.vuNumber()); err != nil {
+		handler.Shutdown()
 		mod.throw(err)
 	}
 
-	_, err = listen(app)
+	mod.coverageLog.register(args.target, routes.all())
+
+	mod.apps[args.target] = app
+
+	if args.options.scope == scopeIteration {
+		mod.scope(args.target)
+	}
+
+	if mod.vu.State() == nil {
+		mod.awaitStart(args)
+	}
+}
+
+// serve starts the mock server with the listener and protocol given by the options, and routes
+// the requests of the mocked target to it. It does not use the runtime.
+func (mod *Module) serve(srv *server, opts *options, vu int64) error {
+	srv.diagnose = opts.diagnose
+	srv.strict, srv.misses = opts.strict, mod.misses
+	srv.delay = opts.delay
+	srv.mode = opts.mode()
+	srv.proto = opts.proto
+
+	network, listenAddr := "tcp", ""
+
+	var err error
+
+	switch {
+	case opts.memory:
+		err = checkDialed(srv.target, opts, "memory")
+		network = pipeNetwork
+	case opts.unix:
+		network = "unix"
+		listenAddr, err = mod.socketPath(srv.target, opts, vu)
+	default:
+		listenAddr, err = listenAddress(srv.target, opts, vu)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := srv.listen(network, listenAddr); err != nil {
+		if errors.Is(err, syscall.EADDRINUSE) {
+			return fmt.Errorf("%w: unable to start mock server for %s on %s", errAddressInUse, srv.target, listenAddr)
+		}
+
+		return err
+	}
+
+	mod.servers[srv.target] = srv
+	mod.running.add(mod)
+
+	if opts.unix || opts.memory || srv.proto != protoHTTP1 {
+		mod.dialers.set(srv.target, srv.dialEntry())
+		mod.dialers.disable(srv.target, mod.disabled[srv.target])
+	} else {
+		mod.lookup[srv.target] = srv.url()
+	}
+
+	return nil
+}
+
+// goHandler is the backend of mock definitions with Go handler, it has nothing to shut down.
+type goHandler struct {
+	http.Handler
+}
+
+func (goHandler) Shutdown() {}
+
+// newBackend returns the application, the handler and the route table of the mock definition.
+// Mock definitions with Go handler (e.g. OAuth2 provider) have no application, the handler
+// gets the raw request and the routes are given by the definition.
+func (mod *Module) newBackend(args *mockArgs) (*sobek.Object, muxpress.Handler, *routeTable) {
+	if args.handler != nil {
+		routes := newRouteTable()
+
+		if args.routes != nil {
+			args.routes(routes)
+		}
+
+		return nil, goHandler{args.handler}, routes
+	}
+
+	app, handler := mod.newApplication(args.settings)
+	routes := mod.trackRoutes(app)
+
+	mod.invoke(app, "use", mod.jwtMiddleware(mod.newJWTVerifier(args.options.jwt)))
+
+	mod.defining = args.target
+
+	_, err := args.callback(mod.runtime().GlobalObject(), app)
+
+	mod.defining = ""
+
 	if err != nil {
 		mod.throw(err)
 	}
 
-	addr := app.Get("host")
-	if addr == nil || len(addr.String()) == 0 {
-		mod.logger.WithField("target", args.target).Warn("mock server not started")
+	return app, handler, routes
+}
+
+// maxPort is the largest valid TCP port number.
+const maxPort = 65535
 
-		return sobek.Undefined()
+// listenAddress returns the listen address of the mock server from mock options.
+// Without options the server listens on a random port of the loopback interface.
+func listenAddress(target string, opts *options, vu int64) (string, error) {
+	host := opts.host
+	if len(host) == 0 {
+		host = backendHost
 	}
 
-	mod.apps[args.target] = app
-	mod.lookup[args.target] = "http://" + addr.String()
+	if opts.port != 0 && opts.basePort != 0 {
+		return "", fmt.Errorf("%w: both port and basePort are given for %s", errInvalidArg, target)
+	}
 
-	return sobek.Undefined()
+	port := opts.port
+
+	if opts.basePort != 0 {
+		port = opts.basePort + vu
+	}
+
+	if port < 0 || port > maxPort {
+		return "", fmt.Errorf("%w: port %d out of range for %s", errInvalidArg, port, target)
+	}
+
+	return net.JoinHostPort(host, strconv.FormatInt(port, 10)), nil
 }
 
-func (mod *Module) mockWithSkip() sobek.Value {
+// checkDialed returns an error if the given option of dialed mocks (socket or memory) is used together with TCP listen options.
+func checkDialed(target string, opts *options, name string) error {
+	if len(opts.host) != 0 || opts.port != 0 || opts.basePort != 0 || (opts.memory && opts.unix) {
+		return fmt.Errorf("%w: %s cannot be used together with host, port, basePort or another listener option for %s",
+			errInvalidArg, name, target)
+	}
+
+	return nil
+}
+
+// socketPath returns the Unix domain socket path of the mock server from mock options.
+// Without explicit path, a unique path is generated in the temporary directory.
+func (mod *Module) socketPath(target string, opts *options, vu int64) (string, error) {
+	if err := checkDialed(target, opts, "socket"); err != nil {
+		return "", err
+	}
+
+	if len(opts.socket) != 0 {
+		return opts.socket, nil
+	}
+
+	mod.sockets++
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("xk6-mock-%d-%d-%d.sock", os.Getpid(), vu, mod.sockets)), nil
+}
+
+// vuNumber returns the value of the __VU global.
+func (mod *Module) vuNumber() int64 {
+	if v := mod.runtime().Get("__VU"); v != nil {
+		return v.ToInteger()
+	}
+
+	return 0
+}
+
+// rewriteTo replaces the URL argument with the URL of the mock selected by name (or target) and returns its target.
+// The mock is used even if it is disabled.
+// The dialed flag is true if the mock is reached by the transport, in this case the URL is kept.
+func (mod *Module) rewriteTo(args []sobek.Value, index int, name string) (string, bool) {
+	loc := args[index].String()
+
+	if target, found := mod.resolve(name); found {
+		name = target
+	}
+
+	if !strings.HasPrefix(loc, name) {
+		mod.throwf("URL %s does not match mock %s", errInvalidArg, loc, name)
+	}
+
+	if _, found := mod.pending[name]; found {
+		mod.startPending(loc)
+	}
+
+	if v, found := mod.lookup[name]; found {
+		args[index] = mod.runtime().ToValue(strings.Replace(loc, name, v, 1))
+
+		return name, false
+	}
+
+	if mod.dialers.get(name) != nil {
+		return name, true
+	}
+
+	mod.throwf("no such mock: %s", errInvalidArg, name)
+
+	return "", false
+}
+
+// startPending starts the pending mock definitions matching given URL.
+// Pending definitions are started only in VU context (setup, teardown), never in init context.
+func (mod *Module) startPending(loc string) {
+	if mod.vu.State() == nil {
+		return
+	}
+
+	for target, args := range mod.pending {
+		if strings.HasPrefix(loc, target) {
+			delete(mod.pending, target)
+			mod.define(args)
+		}
+	}
+}
+
+func (mod *Module) mockFunction() sobek.Value {
 	function := mod.runtime().ToValue(mod.mock).(*sobek.Object) // nolint:forcetypeassert
 
 	function.Set("skip", func(_ sobek.FunctionCall) sobek.Value { return sobek.Undefined() }) // nolint:errcheck
+	function.Set("faker", mod.fakerObject(newFaker(mod.fakerSeed())))                         // nolint:errcheck
+	function.Set("validate", mod.validate)                                                    // nolint:errcheck
+	function.Set("coverage", mod.coverage)                                                    // nolint:errcheck
+	function.Set("reset", mod.reset)                                                          // nolint:errcheck
+	function.Set("list", mod.list)                                                            // nolint:errcheck
+	function.Set("enable", mod.enable)                                                        // nolint:errcheck
+	function.Set("disable", mod.disable)                                                      // nolint:errcheck
+	function.Set("intercept", mod.intercept)                                                  // nolint:errcheck
+	function.Set("cors", mod.cors)                                                            // nolint:errcheck
+	function.Set("auth", mod.authObject())                                                    // nolint:errcheck
+	function.Set("oauth2", mod.oauth2)                                                        // nolint:errcheck
+	function.Set("jwt", mod.jwtObject())                                                      // nolint:errcheck
 
 	return function
 }
 
 func (mod *Module) unmock(target sobek.Value) {
-	if mod.skipMock() {
-		return
+	key := target.String()
+
+	delete(mod.inactive, key)
+
+	if t, found := mod.resolve(key); found {
+		key = t
 	}
 
-	key := target.String()
+	if err := mod.remove(key); err != nil {
+		mod.throw(err)
+	}
+}
 
-	app, ok := mod.apps[key]
-	if !ok {
-		return
+// remove stops the mock server and the application of given target and removes the mock definition.
+// It does not use the runtime, so the mock definitions can be removed by event handlers too.
+// The mock definition is removed even if the server could not be stopped gracefully, the error is returned.
+func (mod *Module) remove(key string) error {
+	delete(mod.pending, key)
+	delete(mod.initDefs, key)
+	delete(mod.disabled, key)
+
+	for name, target := range mod.names {
+		if target == key {
+			delete(mod.names, name)
+		}
+	}
+
+	mod.releaseMarkers(key)
+
+	if _, ok := mod.apps[key]; !ok {
+		return nil
 	}
 
 	delete(mod.apps, key)
 	delete(mod.lookup, key)
+	delete(mod.scoped, key)
+	mod.dialers.remove(key)
 
-	shutdown, _ := sobek.AssertFunction(app.Get("shutdown"))
+	var err error
 
-	if _, err := shutdown(app); err != nil {
-		mod.throw(err)
+	if srv, found := mod.servers[key]; found {
+		delete(mod.servers, key)
+
+		if serr := srv.shutdown(); serr != nil {
+			err = fmt.Errorf("unable to stop mock server of %s gracefully: %w", key, serr)
+		}
 	}
+
+	if len(mod.servers) == 0 {
+		mod.running.remove(mod)
+	}
+
+	return err
 }
 
-func (mod *Module) rewrite(args []sobek.Value, index int) {
+// rewrite replaces the URL argument with the mock server's URL and returns the target of the matching mock
+// (the longest matching one).
+// The URL of mocks listening on Unix domain socket, in memory or talking HTTP/2 is kept, their requests are routed by the VU's transport.
+// Empty string is returned if there is no matching mock.
+func (mod *Module) rewrite(args []sobek.Value, index int) string {
 	loc := args[index].String()
 
-	if strings.HasPrefix(loc, "http://localhost") || strings.HasPrefix(loc, "http://127.") {
-		return
+	if len(mod.pending) != 0 {
+		mod.startPending(loc)
 	}
 
-	for k, v := range mod.lookup {
-		if strings.HasPrefix(loc, k) {
-			args[index] = mod.runtime().ToValue(strings.Replace(loc, k, v, 1))
+	// local URLs (e.g. already rewritten ones) are not rewritten
+	if !strings.HasPrefix(loc, "http://localhost") && !strings.HasPrefix(loc, "http://127.") {
+		target := ""
 
-			break
+		for k := range mod.lookup {
+			if strings.HasPrefix(loc, k) && len(k) > len(target) && !mod.disabled[k] {
+				target = k
+			}
+		}
+
+		if len(target) != 0 {
+			args[index] = mod.runtime().ToValue(strings.Replace(loc, target, mod.lookup[target], 1))
+
+			return target
 		}
 	}
+
+	if mod.dialers.empty() {
+		return ""
+	}
+
+	target, _ := mod.dialers.match(loc)
+	if len(target) != 0 {
+		mod.installTransport()
+	}
+
+	return target
 }
@@ -5,9 +5,12 @@
 package mock
 
 import (
+	"net"
+	"strconv"
 	"testing"
 
 	"github.com/grafana/sobek"
+	"github.com/imroc/req/v3"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -65,7 +68,198 @@ func TestMockUnmok_skip(t *testing.T) {
 
 	assert.NoError(t, helper.vu.Runtime().Set("__VU", 0))
 
+	assert.Panics(t, func() { helper.module.mock(call) })
+
+	call.Arguments = []sobek.Value{
+		helper.vu.Runtime().ToValue("https://example.com"),
+		helper.vu.Runtime().ToValue(func(sobek.FunctionCall) sobek.Value { return nil }),
+	}
+
 	assert.NotPanics(t, func() { helper.module.mock(call) })
+	assert.Contains(t, helper.module.pending, "https://example.com")
+	assert.Empty(t, helper.module.servers)
 
 	assert.NotPanics(t, func() { helper.module.unmock(helper.vu.Runtime().ToValue("https://example.com")) })
+	assert.Empty(t, helper.module.pending)
+}
+
+func TestMockPending(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	assert.NoError(t, helper.vu.Runtime().Set("__VU", 0))
+
+	value, err := helper.vu.Runtime().RunString(`
+mock("https://setup.example.com", app => {
+  app.get("/fixture", (req, res) => res.json({ id: 42 }))
+}, { sync: true })
+mock("https://other.example.com", app => {}, { sync: true })
+mock.list()
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://other.example.com", "https://setup.example.com"}, value.Export())
+	assert.Empty(t, helper.module.servers)
+
+	// no VU state (init context of VU 0): pending mocks are not started
+	assert.Empty(t, helper.module.rewrite([]sobek.Value{helper.vu.Runtime().ToValue("https://setup.example.com/")}, 0))
+	assert.Empty(t, helper.module.servers)
+
+	moveToHTTPContext(helper)
+
+	value, err = helper.vu.Runtime().RunString(`http.get("https://setup.example.com/fixture").json().id`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), value.Export())
+	assert.Contains(t, helper.module.servers, "https://setup.example.com")
+	assert.NotContains(t, helper.module.servers, "https://other.example.com")
+	assert.Contains(t, helper.module.pending, "https://other.example.com")
+
+	helper.module.teardown()
+
+	assert.Empty(t, helper.module.servers)
+	assert.Empty(t, helper.module.pending)
+}
+
+func TestListenAddress(t *testing.T) {
+	t.Parallel()
+
+	target := "https://example.com"
+
+	address := func(opts *options) string {
+		addr, err := listenAddress(target, opts, 1)
+
+		assert.NoError(t, err)
+
+		return addr
+	}
+
+	assert.Equal(t, "127.0.0.1:0", address(new(options)))
+	assert.Equal(t, "127.0.0.1:8080", address(&options{port: 8080}))                    // nolint:exhaustruct
+	assert.Equal(t, "0.0.0.0:9001", address(&options{basePort: 9000, host: "0.0.0.0"})) // nolint:exhaustruct
+	assert.Equal(t, "[::1]:8080", address(&options{port: 8080, host: "::1"}))           // nolint:exhaustruct
+
+	_, err := listenAddress(target, &options{port: 70000}, 1) // nolint:exhaustruct
+
+	assert.ErrorIs(t, err, errInvalidArg)
+
+	_, err = listenAddress(target, &options{port: 1, basePort: 1}, 1) // nolint:exhaustruct
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	assert.NoError(t, err)
+	assert.NoError(t, listener.Close())
+
+	return listener.Addr().(*net.TCPAddr).Port // nolint:forcetypeassert
+}
+
+func TestMockPort(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	port := freePort(t)
+
+	assert.NoError(t, helper.vu.Runtime().Set("port", port))
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://port.example.com", app => {
+  app.get("/", (req, res) => res.text("port"))
+}, { sync: true, port: port, host: "0.0.0.0" })
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:"+strconv.Itoa(port), helper.module.lookup["https://port.example.com"])
+
+	resp, err := req.Get("http://127.0.0.1:" + strconv.Itoa(port))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "port", resp.String())
+
+	_, err = helper.vu.Runtime().RunString(`
+mock("https://other.example.com", app => {}, { sync: true, port: port })
+`)
+
+	assert.ErrorContains(t, err, "address already in use")
+	assert.ErrorContains(t, err, "https://other.example.com")
+	assert.NotContains(t, helper.module.lookup, "https://other.example.com")
+}
+
+func TestMockInProcess(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://inprocess.example.com", app => {
+  app.get("/", (req, res) => res.json({ host: req.host, forwarded: req.get("X-Forwarded-For") }))
+}, { sync: true })
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`http.get("https://inprocess.example.com/").json()`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"host": "inprocess.example.com", "forwarded": ""}, value.Export())
+}
+
+func TestModuleForm(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://upload.example.com", app => {
+  app.post("/upload", (req, res) => {
+    const file = req.files.file
+    res.json({ body: req.body, filename: file.filename, contentType: file.contentType, size: file.size, bytes: file.data.byteLength })
+  })
+  app.post("/form", (req, res) => res.json({ body: req.body, files: req.files, contentType: req.get("content-type") }))
+  app.post("/json", (req, res) => res.json({ body: req.body, files: req.files === undefined }))
+}, { fileLimit: 16 })
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+const base = "https://upload.example.com"
+
+;[
+  http.post(base + "/upload", { title: "hello", file: http.file("hello world", "hello.txt", "text/plain") }).json(),
+  http.post(base + "/form", "a=1&b=2&b=3", { headers: { "Content-Type": "application/x-www-form-urlencoded" } }).json(),
+  http.post(base + "/form", { title: "field longer than the file limit", file: http.file("x", "x.txt") }).json().body,
+  http.post(base + "/json", JSON.stringify({ a: 1 }), { headers: { "Content-Type": "application/json" } }).json(),
+  http.post(base + "/upload", { file: http.file("too large for the file limit", "large.txt") }).status,
+]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"body":        map[string]interface{}{"title": "hello"},
+			"filename":    "hello.txt",
+			"contentType": "text/plain",
+			"size":        float64(11),
+			"bytes":       float64(11),
+		},
+		map[string]interface{}{
+			"body":        map[string]interface{}{"a": "1", "b": []interface{}{"2", "3"}},
+			"files":       map[string]interface{}{},
+			"contentType": "application/x-www-form-urlencoded",
+		},
+		map[string]interface{}{"title": "field longer than the file limit"},
+		map[string]interface{}{"body": map[string]interface{}{"a": float64(1)}, "files": true},
+		int64(413),
+	}, value.Export())
 }
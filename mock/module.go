@@ -7,9 +7,14 @@ package mock
 import (
 	"errors"
 	"fmt"
+	nethttp "net/http"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/grafana/sobek"
 	"github.com/sirupsen/logrus"
+	"github.com/szkiba/xk6-mock/internal/muxpress"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
 	"go.k6.io/k6/js/modules/k6/http"
@@ -17,32 +22,140 @@ import (
 
 type RootModule struct {
 	*http.RootModule
+	unmatched *unmatchedLog
+	coverage  *coverageLog
+	keys      *keyStore
+	recorder  *recorder
+	config    configLoader
+	modules   moduleList
+	once      sync.Once
 }
 
 func New() modules.Module {
-	return &RootModule{RootModule: http.New()}
+	return &RootModule{ // nolint:exhaustruct
+		RootModule: http.New(),
+		unmatched:  newUnmatchedLog(),
+		coverage:   newCoverageLog(),
+		keys:       newKeyStore(),
+		recorder:   new(recorder),
+	}
 }
 
 func (root *RootModule) NewModuleInstance(vu modules.VU) modules.Instance { // nolint:varnamelen
-	return &Module{
+	root.once.Do(func() { root.reportAtTestEnd(vu) })
+
+	logger, logLevel := leveledLogger(newLogger(vu))
+	calls := newSyncCalls()
+
+	mod := &Module{
 		ModuleInstance: root.RootModule.NewModuleInstance(vu).(*http.ModuleInstance), // nolint:forcetypeassert
 		vu:             vu,
-		appCtor:        newApplicationCtor(vu, false),
-		appCtorSync:    newApplicationCtor(vu, true),
-		logger:         newLogger(vu),
-		apps:           make(map[string]*sobek.Object),
-		lookup:         make(map[string]string),
+		calls:          calls,
+		appOptions: [...][]muxpress.Option{
+			modeAuto:  newApplicationOptions(vu, newAutoRunner(vu, calls)),
+			modeSync:  newApplicationOptions(vu, nil),
+			modeAsync: newApplicationOptions(vu, newRunner(vu)),
+		},
+		logger:      logger,
+		logLevel:    logLevel,
+		metrics:     newMetrics(vu),
+		apps:        make(map[string]*sobek.Object),
+		lookup:      make(map[string]string),
+		servers:     make(map[string]*server),
+		dialers:     newDialTable(),
+		unmatched:   root.unmatched,
+		coverageLog: root.coverage,
+		keys:        root.keys,
+		misses:      new(missLog),
+		recorder:    root.recorder,
+		loader:      &root.config,
+		scoped:      make(map[string]struct{}),
+		pending:     make(map[string]*mockArgs),
+		initDefs:    make(map[string]*mockArgs),
+		names:       make(map[string]string),
+		disabled:    make(map[string]bool),
+		inactive:    make(map[string]string),
+		selector:    envSwitch(vu),
+		markers:     make(map[*sobek.Object]*marker),
+		running:     &root.modules,
 	}
+
+	for mode, opts := range mod.appOptions {
+		mod.appCtors[mode] = newApplicationCtor(vu, opts)
+	}
+
+	return mod
 }
 
 type Module struct {
 	*http.ModuleInstance
 	vu          modules.VU
-	appCtor     func(sobek.ConstructorCall) *sobek.Object
-	appCtorSync func(sobek.ConstructorCall) *sobek.Object
+	appOptions  [3][]muxpress.Option
+	appCtors    [3]func(sobek.ConstructorCall) *sobek.Object
+	calls       *syncCalls
+	wrapped     bool
 	apps        map[string]*sobek.Object
 	lookup      map[string]string
+	servers     map[string]*server
+	dialers     *dialTable
+	browser     *nethttp.Client
+	scoped      map[string]struct{}
+	pending     map[string]*mockArgs
+	initDefs    map[string]*mockArgs
+	names       map[string]string
+	disabled    map[string]bool
+	inactive    map[string]string
+	selector    *mockSwitch
+	markers     map[*sobek.Object]*marker
+	running     *moduleList
+	iterStart   func()
+	iterEnd     func()
+	sockets     int
 	logger      logrus.FieldLogger
+	logLevel    *logrus.Logger
+	loader      *configLoader
+	config      *config
+	metrics     *mockMetrics
+	unmatched   *unmatchedLog
+	coverageLog *coverageLog
+	keys        *keyStore
+	misses      *missLog
+	recorder    *recorder
+	defining    string
+}
+
+// marker is the Go side of a JavaScript object created by the module (CORS middlewares, OAuth2 providers).
+// The owner is the target of the mock definition the object belongs to, markers are removed together
+// with the mock definition. Objects created outside of mock definitions have empty owner.
+type marker struct {
+	owner    string
+	cors     *corsPolicy
+	provider *oauth2Provider
+}
+
+// mark registers the marker of given object.
+func (mod *Module) mark(obj *sobek.Object, m *marker) {
+	mod.markers[obj] = m
+}
+
+// releaseMarkers removes the markers of given owner.
+func (mod *Module) releaseMarkers(owner string) {
+	for obj, m := range mod.markers {
+		if m.owner == owner {
+			delete(mod.markers, obj)
+		}
+	}
+}
+
+// marked returns the marker of given value, or an empty marker if the value was not created by the module.
+func (mod *Module) marked(value sobek.Value) *marker {
+	if obj, isObj := value.(*sobek.Object); isObj {
+		if m, found := mod.markers[obj]; found {
+			return m
+		}
+	}
+
+	return new(marker)
 }
 
 var (
@@ -76,20 +189,74 @@ func (mod *Module) Exports() modules.Exports {
 
 	mustSet("unmock", mod.unmock)
 	mustSet("Application", mod.applicationCtor())
-	mustSet("mock", mod.mockWithSkip())
+	mustSet("mock", mod.mockFunction())
 
 	return exports
 }
 
 type options struct {
-	sync bool
-	skip bool
+	sync      bool
+	skip      bool
+	diagnose  bool
+	host      string
+	port      int64
+	basePort  int64
+	bodyLimit int64
+	fileLimit int64
+	unix      bool
+	socket    string
+	memory    bool
+	proto     string
+	tls       bool
+	strict    bool
+	delay     time.Duration
+	scope     string
+	name      string
+	jwt       *sobek.Object
+	given     map[string]struct{}
+}
+
+// mode returns the operation mode of the application: synchronous or asynchronous if sync is given,
+// otherwise it is decided per request.
+func (opts *options) mode() int {
+	if _, given := opts.given["sync"]; !given {
+		return modeAuto
+	}
+
+	if opts.sync {
+		return modeSync
+	}
+
+	return modeAsync
+}
+
+// clone returns a copy of the options, which can be modified without changing the original.
+func (opts *options) clone() *options {
+	copied := *opts
+	copied.given = make(map[string]struct{}, len(opts.given))
+
+	for key := range opts.given {
+		copied.given[key] = struct{}{}
+	}
+
+	return &copied
+}
+
+// formLimits returns the muxpress option of form body size limits.
+func (opts *options) formLimits() muxpress.Option {
+	return muxpress.WithFormLimits(opts.bodyLimit, opts.fileLimit)
 }
 
 func getopts(value sobek.Value) *options {
-	opts := new(options)
+	opts := &options{given: make(map[string]struct{})} // nolint:exhaustruct
 
 	if obj, ok := value.(*sobek.Object); ok {
+		for _, key := range obj.Keys() {
+			if v := obj.Get(key); v != nil && !sobek.IsUndefined(v) {
+				opts.given[key] = struct{}{}
+			}
+		}
+
 		flag := func(name string) bool {
 			v := obj.Get(name)
 
@@ -98,9 +265,65 @@ func getopts(value sobek.Value) *options {
 
 		opts.sync = flag("sync")
 		opts.skip = flag("skip")
+		opts.diagnose = flag("diagnose")
+		opts.memory = flag("memory")
+		opts.strict = flag("strict")
+		opts.tls = flag("tls")
+
+		integer := func(name string) int64 {
+			v := obj.Get(name)
+			if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+				return 0
+			}
+
+			return v.ToInteger()
+		}
+
+		opts.port = integer("port")
+		opts.basePort = integer("basePort")
+		opts.bodyLimit = integer("bodyLimit")
+		opts.fileLimit = integer("fileLimit")
+		opts.delay = time.Duration(integer("delay")) * time.Millisecond
+
+		if v := obj.Get("host"); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+			opts.host = v.String()
+		}
+
+		if v := obj.Get("name"); v != nil && v.ExportType() != nil {
+			opts.name = v.String()
+		}
+
+		if v := obj.Get("scope"); v != nil && v.ExportType() != nil {
+			opts.scope = v.String()
+		}
+
+		if v := obj.Get("http2"); v != nil && v.ExportType() != nil {
+			if v.ExportType().Kind() == reflect.String {
+				opts.proto = v.String()
+			} else if v.ToBoolean() {
+				opts.proto = protoH2
+			}
+		}
+
+		if v, isObj := obj.Get("jwt").(*sobek.Object); isObj {
+			opts.jwt = v
+		}
+
+		if v := obj.Get("socket"); v != nil && v.ExportType() != nil {
+			if v.ExportType().Kind() == reflect.String {
+				opts.unix, opts.socket = true, v.String()
+			} else {
+				opts.unix = v.ToBoolean()
+			}
+		}
 	}
 
 	return opts
 }
 
-var errInvalidArg = errors.New("invalid argument")
+var (
+	errInvalidArg   = errors.New("invalid argument")
+	errAddressInUse = errors.New("address already in use")
+	errSyncCall     = errors.New("synchronous call to asynchronous mock")
+	errUnmatched    = errors.New("no route matches mock request")
+)
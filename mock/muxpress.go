@@ -5,9 +5,12 @@
 package mock
 
 import (
+	"sync"
+	"sync/atomic"
+
 	"github.com/grafana/sobek"
 	"github.com/sirupsen/logrus"
-	"github.com/szkiba/muxpress"
+	"github.com/szkiba/xk6-mock/internal/muxpress"
 	"go.k6.io/k6/js/common"
 	"go.k6.io/k6/js/modules"
 )
@@ -18,6 +21,100 @@ func newRunner(vu modules.VU) muxpress.RunnerFunc { // nolint:varnamelen
 	}
 }
 
+// syncCalls tracks whether the VU is blocked in a synchronous http call.
+type syncCalls struct {
+	mu      sync.Mutex
+	idle    *sync.Cond
+	blocked int
+	running int
+
+	// runtime serializes the middlewares running while the VU is blocked
+	runtime sync.Mutex
+}
+
+func newSyncCalls() *syncCalls {
+	calls := new(syncCalls)
+	calls.idle = sync.NewCond(&calls.mu)
+
+	return calls
+}
+
+// enter marks the VU as blocked in a synchronous call.
+func (calls *syncCalls) enter() {
+	calls.mu.Lock()
+	defer calls.mu.Unlock()
+
+	calls.blocked++
+}
+
+// exit marks the end of a synchronous call. Leaving the outermost call waits for the middlewares
+// running meanwhile, so the VU does not continue while they are using the runtime.
+func (calls *syncCalls) exit() {
+	calls.mu.Lock()
+	defer calls.mu.Unlock()
+
+	calls.blocked--
+
+	for calls.blocked == 0 && calls.running != 0 {
+		calls.idle.Wait()
+	}
+}
+
+// start registers a middleware run if the VU is blocked in a synchronous call.
+// It returns false if the VU is not blocked.
+func (calls *syncCalls) start() bool {
+	calls.mu.Lock()
+	defer calls.mu.Unlock()
+
+	if calls.blocked == 0 {
+		return false
+	}
+
+	calls.running++
+
+	return true
+}
+
+// done marks the end of a middleware run registered by start.
+func (calls *syncCalls) done() {
+	calls.mu.Lock()
+	defer calls.mu.Unlock()
+
+	calls.running--
+	calls.idle.Broadcast()
+}
+
+// newAutoRunner returns a runner which executes middlewares directly while the VU is blocked in a synchronous call,
+// otherwise it schedules middlewares on the event loop. Middleware errors (the request is answered with 500)
+// are logged, they don't abort the VU.
+func newAutoRunner(vu modules.VU, calls *syncCalls) muxpress.RunnerFunc { // nolint:varnamelen
+	async := newRunner(vu)
+	logger := newLogger(vu)
+
+	return func(fn func() error) {
+		run := func() error {
+			if err := fn(); err != nil {
+				logger.WithError(err).Error("mock handler failed")
+			}
+
+			return nil
+		}
+
+		if !calls.start() {
+			async(run)
+
+			return
+		}
+
+		defer calls.done()
+
+		calls.runtime.Lock()
+		defer calls.runtime.Unlock()
+
+		run() // nolint:errcheck
+	}
+}
+
 func newLogger(vu modules.VU) logrus.FieldLogger { // nolint:varnamelen
 	var logger logrus.FieldLogger
 
@@ -34,13 +131,26 @@ func newLogger(vu modules.VU) logrus.FieldLogger { // nolint:varnamelen
 	return logger.WithField("module", "mock")
 }
 
-func newApplicationCtor(vu modules.VU, sync bool) func(sobek.ConstructorCall) *sobek.Object { // nolint:varnamelen
+// Operation modes of applications.
+const (
+	modeAuto = iota
+	modeSync
+	modeAsync
+)
+
+// newApplicationOptions returns the muxpress options of applications using given runner.
+// The nil runner means the default (synchronous) runner of muxpress.
+func newApplicationOptions(vu modules.VU, runner muxpress.RunnerFunc) []muxpress.Option { // nolint:varnamelen
 	opts := []muxpress.Option{muxpress.WithLogger(newLogger(vu))}
 
-	if !sync {
-		opts = append(opts, muxpress.WithRunner(newRunner(vu)))
+	if runner != nil {
+		opts = append(opts, muxpress.WithRunner(runner))
 	}
 
+	return opts
+}
+
+func newApplicationCtor(vu modules.VU, opts []muxpress.Option) func(sobek.ConstructorCall) *sobek.Object { // nolint:varnamelen,lll
 	ctor, err := muxpress.NewApplicationConstructor(vu.Runtime(), opts...)
 	if err != nil {
 		common.Throw(vu.Runtime(), err)
@@ -51,35 +161,84 @@ func newApplicationCtor(vu modules.VU, sync bool) func(sobek.ConstructorCall) *s
 
 func (mod *Module) applicationCtor() func(sobek.ConstructorCall) *sobek.Object {
 	return func(call sobek.ConstructorCall) *sobek.Object {
-		if len(call.Arguments) == 0 || !getopts(call.Argument(0)).sync {
-			return mod.appCtor(call)
+		if len(call.Arguments) == 0 {
+			return mod.appCtors[modeAuto](call)
 		}
 
-		return mod.appCtorSync(call)
+		opts := getopts(call.Argument(0))
+
+		if opts.bodyLimit == 0 && opts.fileLimit == 0 {
+			return mod.appCtors[opts.mode()](call)
+		}
+
+		return newApplicationCtor(mod.vu, withOption(mod.appOptions[opts.mode()], opts.formLimits()))(call)
 	}
 }
 
-func (mod *Module) newApplication(sync bool) (*sobek.Object, sobek.Callable) {
-	from := mod.appCtor
+// appSettings are the settings of a mock definition's application which can be changed while the application
+// is running: the defaults of options.ext.mock are applied to the definitions of the init context when the VU starts.
+type appSettings struct {
+	mode      atomic.Int32
+	bodyLimit atomic.Int64
+	fileLimit atomic.Int64
+}
+
+func newAppSettings(opts *options) *appSettings {
+	settings := new(appSettings)
+	settings.set(opts)
+
+	return settings
+}
+
+func (settings *appSettings) set(opts *options) {
+	settings.mode.Store(int32(opts.mode()))
+	settings.bodyLimit.Store(opts.bodyLimit)
+	settings.fileLimit.Store(opts.fileLimit)
+}
+
+func (settings *appSettings) limits() (int64, int64) {
+	return settings.bodyLimit.Load(), settings.fileLimit.Load()
+}
+
+// newSyncRunner returns a runner which executes middlewares synchronously, like the default runner of muxpress.
+func newSyncRunner() muxpress.RunnerFunc {
+	var mu sync.Mutex
 
-	if sync {
-		from = mod.appCtorSync
+	return func(fn func() error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := fn(); err != nil {
+			panic(err)
+		}
 	}
+}
 
-	ctor, assertOK := sobek.AssertConstructor(mod.runtime().ToValue(from))
-	if !assertOK {
-		mod.throwf("invalid constructor", errInvalidArg)
+// newApplication returns a new application of a mock definition and its handler.
+// The application is not listening, its handler is served by the mock server.
+// The middlewares are executed by the runner of the mode given by the settings.
+func (mod *Module) newApplication(settings *appSettings) (*sobek.Object, muxpress.Handler) {
+	runners := [...]muxpress.RunnerFunc{
+		modeAuto:  newAutoRunner(mod.vu, mod.calls),
+		modeSync:  newSyncRunner(),
+		modeAsync: newRunner(mod.vu),
 	}
 
-	app, err := ctor(mod.runtime().NewObject())
+	app, handler, err := muxpress.NewApplication(mod.runtime(),
+		muxpress.WithLogger(newLogger(mod.vu)),
+		muxpress.WithRunner(func(fn func() error) { runners[settings.mode.Load()](fn) }),
+		muxpress.WithFormLimitsFunc(settings.limits),
+	)
 	if err != nil {
 		mod.throw(err)
 	}
 
-	listen, assertOK := sobek.AssertFunction(app.Get("listen"))
-	if !assertOK {
-		mod.throwf("missing listen method", errInvalidArg)
-	}
+	return app, handler
+}
+
+// withOption returns the options extended by extra options, without modifying the original slice.
+func withOption(opts []muxpress.Option, extra ...muxpress.Option) []muxpress.Option {
+	all := make([]muxpress.Option, 0, len(opts)+len(extra))
 
-	return app, listen
+	return append(append(all, opts...), extra...)
 }
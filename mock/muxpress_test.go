@@ -87,6 +87,30 @@ func TestGetopts(t *testing.T) {
 
 	assert.True(t, opts.sync)
 	assert.True(t, opts.skip)
+	assert.Zero(t, opts.port)
+	assert.Empty(t, opts.host)
+
+	assert.NoError(t, obj.Set("port", 8080))
+	assert.NoError(t, obj.Set("basePort", 9000))
+	assert.NoError(t, obj.Set("host", "0.0.0.0"))
+
+	opts = getopts(obj)
+
+	assert.Equal(t, int64(8080), opts.port)
+	assert.Equal(t, int64(9000), opts.basePort)
+	assert.Equal(t, "0.0.0.0", opts.host)
+	assert.False(t, opts.unix)
+
+	assert.NoError(t, obj.Set("socket", sobek.Null()))
+	assert.False(t, getopts(obj).unix)
+
+	assert.NoError(t, obj.Set("socket", true))
+	assert.True(t, getopts(obj).unix)
+	assert.Empty(t, getopts(obj).socket)
+
+	assert.NoError(t, obj.Set("socket", "/tmp/mock.sock"))
+	assert.True(t, getopts(obj).unix)
+	assert.Equal(t, "/tmp/mock.sock", getopts(obj).socket)
 }
 
 func TestNewRunner(t *testing.T) {
@@ -125,6 +149,65 @@ func TestNewRunner(t *testing.T) {
 	assert.True(t, jobCalled)
 }
 
+func TestNewAutoRunner(t *testing.T) {
+	t.Parallel()
+
+	vu := modulestest.NewRuntime(t).VU // nolint:varnamelen
+
+	enqueued := 0
+
+	vu.RegisterCallbackField = func() func(func() error) {
+		return func(f func() error) {
+			enqueued++
+
+			f() // nolint:errcheck
+		}
+	}
+
+	calls := newSyncCalls()
+	runner := newAutoRunner(vu, calls)
+	jobs := 0
+	job := func() error {
+		jobs++
+
+		return nil
+	}
+
+	runner(job)
+
+	assert.Equal(t, 1, enqueued)
+	assert.Equal(t, 1, jobs)
+
+	calls.enter()
+	runner(job)
+	calls.exit()
+
+	assert.Equal(t, 1, enqueued)
+	assert.Equal(t, 2, jobs)
+
+	runner(job)
+
+	assert.Equal(t, 2, enqueued)
+	assert.Equal(t, 3, jobs)
+
+	calls.enter()
+
+	// middleware errors are logged, the lock of the calls is not held by the middleware
+	assert.NotPanics(t, func() { runner(func() error { return errInvalidArg }) })
+
+	runner(func() error {
+		calls.enter()
+		calls.exit()
+
+		return job()
+	})
+
+	calls.exit()
+
+	assert.Equal(t, 2, enqueued)
+	assert.Equal(t, 4, jobs)
+}
+
 func TestApplicationCtor(t *testing.T) {
 	t.Parallel()
 
@@ -132,46 +215,47 @@ func TestApplicationCtor(t *testing.T) {
 
 	ctor := module.applicationCtor()
 
+	called := -1
+
+	for mode := range module.appCtors {
+		mode := mode
+
+		module.appCtors[mode] = func(cc sobek.ConstructorCall) *sobek.Object {
+			called = mode
+
+			return nil
+		}
+	}
+
 	call := sobek.ConstructorCall{
 		This:      vu.Runtime().GlobalObject(),
 		NewTarget: vu.Runtime().NewObject(),
 		Arguments: []sobek.Value{},
 	}
 
-	appCtorCalled := false
+	ctor(call)
 
-	module.appCtor = func(cc sobek.ConstructorCall) *sobek.Object {
-		appCtorCalled = true
+	assert.Equal(t, modeAuto, called)
 
-		return nil
-	}
+	opts := vu.Runtime().NewObject()
 
-	module.appCtorSync = nil
+	call.Arguments = append(call.Arguments, vu.Runtime().ToValue(opts))
 
 	ctor(call)
 
-	assert.True(t, appCtorCalled)
-
-	appCtorSyncCalled := false
-
-	appCtorCalled = false
-
-	module.appCtorSync = func(cc sobek.ConstructorCall) *sobek.Object {
-		appCtorSyncCalled = true
+	assert.Equal(t, modeAuto, called)
 
-		return nil
-	}
+	assert.NoError(t, opts.Set("sync", true))
 
-	opts := vu.Runtime().NewObject()
+	ctor(call)
 
-	assert.NoError(t, opts.Set("sync", true))
+	assert.Equal(t, modeSync, called)
 
-	call.Arguments = append(call.Arguments, vu.Runtime().ToValue(opts))
+	assert.NoError(t, opts.Set("sync", false))
 
 	ctor(call)
 
-	assert.False(t, appCtorCalled)
-	assert.True(t, appCtorSyncCalled)
+	assert.Equal(t, modeAsync, called)
 }
 
 func newModule(t *testing.T) (*Module, modules.VU) {
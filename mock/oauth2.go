@@ -0,0 +1,610 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/sobek"
+)
+
+// Endpoints of the OAuth2/OIDC provider, relative to the issuer.
+const (
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+	oidcJWKSPath      = "/.well-known/jwks.json"
+	oauth2TokenPath   = "/token"
+	oidcUserinfoPath  = "/userinfo"
+)
+
+// OAuth2 grant types supported by the provider.
+const (
+	grantClientCredentials = "client_credentials"
+	grantPassword          = "password"
+	grantRefreshToken      = "refresh_token"
+)
+
+const (
+	authOAuth2 = "oauth2"
+
+	defaultTokenLifetime = time.Hour
+)
+
+type oauth2Client struct {
+	secret string
+	scopes []string
+}
+
+type oauth2User struct {
+	password string
+	claims   map[string]interface{}
+}
+
+// oauth2Grant is the authorization behind a refresh token.
+type oauth2Grant struct {
+	client string
+	user   string
+	scopes []string
+}
+
+// grantStore holds the grants of the refresh tokens issued by a provider.
+type grantStore struct {
+	mu     sync.Mutex
+	grants map[string]*oauth2Grant
+}
+
+func (store *grantStore) add(token string, grant *oauth2Grant) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.grants[token] = grant
+}
+
+// use calls fn with the grant of the refresh token (nil if there is no such token).
+// The token is removed if fn succeeds, so it can be used only once, even by concurrent requests.
+func (store *grantStore) use(token string, fn func(*oauth2Grant) (*oauth2Grant, error)) (*oauth2Grant, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	result, err := fn(store.grants[token])
+	if err == nil {
+		delete(store.grants, token)
+	}
+
+	return result, err
+}
+
+// oauth2Provider is an OAuth2 authorization server and OpenID Connect provider.
+// It is served by the mock server directly, without muxpress application.
+type oauth2Provider struct {
+	issuer   string
+	base     string
+	clients  map[string]*oauth2Client
+	users    map[string]*oauth2User
+	scopes   []string
+	lifetime time.Duration
+	key      *jwtKey
+	now      func() time.Time
+	refresh  *grantStore
+}
+
+// oauth2Error is an error response of the token endpoint (RFC 6749 section 5.2).
+type oauth2Error struct {
+	status      int
+	code        string
+	description string
+}
+
+func (e *oauth2Error) Error() string {
+	return e.code + ": " + e.description
+}
+
+func newOAuth2Error(status int, code string, format string, args ...interface{}) *oauth2Error {
+	return &oauth2Error{status: status, code: code, description: fmt.Sprintf(format, args...)}
+}
+
+func (provider *oauth2Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, provider.base)
+
+	switch {
+	case path == oidcDiscoveryPath && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, provider.discovery())
+	case path == oidcJWKSPath && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []interface{}{provider.key.jwk()}})
+	case path == oauth2TokenPath && r.Method == http.MethodPost:
+		provider.token(w, r)
+	case path == oidcUserinfoPath && (r.Method == http.MethodGet || r.Method == http.MethodPost):
+		provider.userinfo(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not_found", "error_description": "no such endpoint"})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	json.NewEncoder(w).Encode(body) // nolint:errcheck,errchkjson
+}
+
+func (provider *oauth2Provider) discovery() map[string]interface{} {
+	doc := map[string]interface{}{
+		"issuer":                                provider.issuer,
+		"token_endpoint":                        provider.issuer + oauth2TokenPath,
+		"jwks_uri":                              provider.issuer + oidcJWKSPath,
+		"userinfo_endpoint":                     provider.issuer + oidcUserinfoPath,
+		"grant_types_supported":                 []string{grantClientCredentials, grantPassword, grantRefreshToken},
+		"response_types_supported":              []string{"token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{provider.key.alg},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	}
+
+	if len(provider.scopes) != 0 {
+		doc["scopes_supported"] = provider.scopes
+	}
+
+	return doc
+}
+
+func (provider *oauth2Provider) token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	resp, err := provider.grant(r)
+	if err != nil {
+		oerr, ok := err.(*oauth2Error) // nolint:errorlint
+		if !ok {
+			oerr = newOAuth2Error(http.StatusInternalServerError, "server_error", "%s", err.Error())
+		}
+
+		if oerr.status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+defaultRealm+`"`)
+		}
+
+		writeJSON(w, oerr.status, map[string]string{"error": oerr.code, "error_description": oerr.description})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// grant handles a token request and returns the token response.
+func (provider *oauth2Provider) grant(r *http.Request) (map[string]interface{}, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, newOAuth2Error(http.StatusBadRequest, "invalid_request", "%s", err.Error())
+	}
+
+	clientID, client, err := provider.authenticateClient(r)
+	if err != nil {
+		return nil, err
+	}
+
+	grant := &oauth2Grant{client: clientID} // nolint:exhaustruct
+
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case grantClientCredentials:
+		if grant.scopes, err = provider.grantScopes(client, r.PostForm.Get("scope"), nil); err != nil {
+			return nil, err
+		}
+	case grantPassword:
+		user, found := provider.users[r.PostForm.Get("username")]
+		if !found || !equalSecret(r.PostForm.Get("password"), user.password) {
+			return nil, newOAuth2Error(http.StatusBadRequest, "invalid_grant", "invalid username or password")
+		}
+
+		grant.user = r.PostForm.Get("username")
+
+		if grant.scopes, err = provider.grantScopes(client, r.PostForm.Get("scope"), nil); err != nil {
+			return nil, err
+		}
+	case grantRefreshToken:
+		if grant, err = provider.redeem(clientID, r.PostForm.Get("refresh_token"), r.PostForm.Get("scope")); err != nil {
+			return nil, err
+		}
+	case "":
+		return nil, newOAuth2Error(http.StatusBadRequest, "invalid_request", "missing grant_type")
+	default:
+		return nil, newOAuth2Error(http.StatusBadRequest, "unsupported_grant_type", "unsupported grant type: %s", grantType)
+	}
+
+	return provider.issue(grant)
+}
+
+// authenticateClient returns the client of the request, authenticated by HTTP Basic authentication
+// or by client_id and client_secret form parameters. Clients without secret are public clients.
+func (provider *oauth2Provider) authenticateClient(r *http.Request) (string, *oauth2Client, error) {
+	id, secret, basic := r.BasicAuth()
+
+	if basic {
+		// client credentials are form encoded before Basic encoding (RFC 6749 section 2.3.1)
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	client, found := provider.clients[id]
+	if !found || (len(client.secret) != 0 && !equalSecret(secret, client.secret)) {
+		return "", nil, newOAuth2Error(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	}
+
+	return id, client, nil
+}
+
+// grantScopes returns the scopes granted for the requested ones. Without requested scopes, all allowed scopes are granted.
+// Allowed scopes are the scopes of the client, or the scopes of the provider. The limit narrows them further if not nil.
+func (provider *oauth2Provider) grantScopes(client *oauth2Client, requested string, limit []string) ([]string, error) {
+	allowed := client.scopes
+	if allowed == nil {
+		allowed = provider.scopes
+	}
+
+	if limit != nil {
+		allowed = limit
+	}
+
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return allowed, nil
+	}
+
+	if allowed == nil {
+		return scopes, nil
+	}
+
+	for _, scope := range scopes {
+		if !contains(allowed, scope) {
+			return nil, newOAuth2Error(http.StatusBadRequest, "invalid_scope", "scope not allowed: %s", scope)
+		}
+	}
+
+	return scopes, nil
+}
+
+// redeem exchanges a refresh token for its grant. Refresh tokens are rotated, they can be used only once.
+// The requested scopes cannot exceed the originally granted ones.
+func (provider *oauth2Provider) redeem(clientID string, token string, requested string) (*oauth2Grant, error) {
+	return provider.refresh.use(token, func(grant *oauth2Grant) (*oauth2Grant, error) {
+		if grant == nil || grant.client != clientID {
+			return nil, newOAuth2Error(http.StatusBadRequest, "invalid_grant", "invalid refresh token")
+		}
+
+		scopes, err := provider.grantScopes(provider.clients[clientID], requested, grant.scopes)
+		if err != nil {
+			return nil, err
+		}
+
+		return &oauth2Grant{client: grant.client, user: grant.user, scopes: scopes}, nil
+	})
+}
+
+// issue returns the token response of a grant. Refresh token is issued only for users,
+// ID token is issued if the openid scope is granted.
+func (provider *oauth2Provider) issue(grant *oauth2Grant) (map[string]interface{}, error) {
+	now := provider.now()
+
+	subject := grant.client
+	if len(grant.user) != 0 {
+		subject = grant.user
+	}
+
+	claims := map[string]interface{}{
+		"iss":       provider.issuer,
+		"sub":       subject,
+		"aud":       grant.client,
+		"client_id": grant.client,
+		"iat":       now.Unix(),
+		"exp":       now.Add(provider.lifetime).Unix(),
+		"jti":       randomToken(),
+	}
+
+	if len(grant.scopes) != 0 {
+		claims["scope"] = strings.Join(grant.scopes, " ")
+	}
+
+	access, err := provider.key.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := map[string]interface{}{
+		"access_token": access,
+		"token_type":   "Bearer",
+		"expires_in":   int64(provider.lifetime / time.Second),
+	}
+
+	if len(grant.scopes) != 0 {
+		resp["scope"] = claims["scope"]
+	}
+
+	if len(grant.user) == 0 {
+		return resp, nil
+	}
+
+	if contains(grant.scopes, "openid") {
+		if resp["id_token"], err = provider.key.sign(provider.idClaims(grant, now)); err != nil {
+			return nil, err
+		}
+	}
+
+	refresh := randomToken()
+
+	provider.refresh.add(refresh, grant)
+
+	resp["refresh_token"] = refresh
+
+	return resp, nil
+}
+
+func (provider *oauth2Provider) idClaims(grant *oauth2Grant, now time.Time) map[string]interface{} {
+	claims := provider.userClaims(grant.user)
+
+	claims["iss"] = provider.issuer
+	claims["aud"] = grant.client
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(provider.lifetime).Unix()
+
+	return claims
+}
+
+// userClaims returns the claims of the user, including the sub claim.
+func (provider *oauth2Provider) userClaims(name string) map[string]interface{} {
+	claims := map[string]interface{}{}
+
+	if user, found := provider.users[name]; found {
+		for key, value := range user.claims {
+			claims[key] = value
+		}
+	}
+
+	claims["sub"] = name
+
+	return claims
+}
+
+// userinfo returns the claims of the user of the access token.
+func (provider *oauth2Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	token, err := provider.verify(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+defaultRealm+`", error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token", "error_description": err.Error()})
+
+		return
+	}
+
+	sub, _ := token.claims["sub"].(string)
+
+	if _, found := provider.users[sub]; !found {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "insufficient_scope", "error_description": "no user"})
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, provider.userClaims(sub))
+}
+
+// verify returns the verified access token of the request.
+func (provider *oauth2Provider) verify(r *http.Request) (*jwtToken, error) {
+	str, found := bearerToken(r)
+	if !found {
+		return nil, fmt.Errorf("%w: missing bearer token", errInvalidToken)
+	}
+
+	return provider.verifier().verify(str)
+}
+
+// verifier returns the verifier of the tokens issued by the provider.
+func (provider *oauth2Provider) verifier() *jwtVerifier {
+	return &jwtVerifier{keys: []*jwtKey{provider.key}, issuer: provider.issuer, audience: "", now: provider.now}
+}
+
+func randomToken() string {
+	buff := make([]byte, 24) // nolint:gomnd
+
+	rand.Read(buff) // nolint:errcheck
+
+	return base64.RawURLEncoding.EncodeToString(buff)
+}
+
+// oauth2Auth checks the access tokens issued by an OAuth2 provider mock.
+type oauth2Auth struct {
+	provider *oauth2Provider
+	scopes   []string
+	audience string
+	realm    string
+}
+
+func (auth *oauth2Auth) needsBody() bool { return false }
+
+func (auth *oauth2Auth) authenticate(r *http.Request, _ []byte) *authFailure {
+	challenge := `Bearer realm="` + auth.realm + `"`
+
+	if _, found := bearerToken(r); !found {
+		return jsonFailure(authOAuth2, http.StatusUnauthorized, challenge, "unauthorized", "missing bearer token")
+	}
+
+	token, err := auth.provider.verify(r)
+	if err != nil {
+		return jsonFailure(authOAuth2, http.StatusUnauthorized, challenge+`, error="invalid_token"`, "invalid_token", err.Error())
+	}
+
+	if len(auth.audience) != 0 && !token.hasAudience(auth.audience) {
+		return jsonFailure(authOAuth2, http.StatusUnauthorized, challenge+`, error="invalid_token"`,
+			"invalid_token", "unexpected audience")
+	}
+
+	granted := token.scopes()
+
+	for _, scope := range auth.scopes {
+		if !contains(granted, scope) {
+			return jsonFailure(authOAuth2, http.StatusForbidden,
+				challenge+`, error="insufficient_scope", scope="`+strings.Join(auth.scopes, " ")+`"`,
+				"insufficient_scope", "missing scope: "+scope)
+		}
+	}
+
+	return nil
+}
+
+// oauth2 defines a mock OAuth2/OIDC provider on given target. The returned object contains the issuer
+// and the auth method, which returns a middleware accepting the access tokens of the provider.
+func (mod *Module) oauth2(target string, value sobek.Value) sobek.Value {
+	if len(target) == 0 {
+		mod.throwf("missing or empty mock target", errInvalidArg)
+	}
+
+	opts, isObj := value.(*sobek.Object)
+	if !isObj {
+		opts = mod.runtime().NewObject()
+	}
+
+	issuer := strings.TrimSuffix(target, "/")
+
+	key, err := mod.keys.get(issuer)
+	if err != nil {
+		mod.throw(err)
+	}
+
+	provider := &oauth2Provider{
+		issuer:   issuer,
+		clients:  mod.oauth2Clients(opts),
+		users:    mod.oauth2Users(opts),
+		scopes:   mod.optStrings(opts, "scopes"),
+		lifetime: defaultTokenLifetime,
+		key:      key,
+		now:      time.Now,
+		refresh:  mod.keys.refreshTokens(issuer),
+	}
+
+	if loc, err := url.Parse(issuer); err == nil {
+		provider.base = loc.Path
+	}
+
+	if v := opts.Get("expiresIn"); v != nil && !sobek.IsUndefined(v) && !sobek.IsNull(v) {
+		if provider.lifetime = time.Duration(v.ToInteger()) * time.Second; provider.lifetime <= 0 {
+			mod.throwf("expiresIn must be positive", errInvalidArg)
+		}
+	}
+
+	mod.register(&mockArgs{
+		target:  target,
+		routes:  oauth2Routes,
+		options: getopts(opts),
+		handler: provider,
+	})
+
+	obj := mod.runtime().NewObject()
+
+	mod.mark(obj, &marker{owner: target, provider: provider}) // nolint:exhaustruct
+
+	obj.Set("issuer", issuer)                             // nolint:errcheck
+	obj.Set("auth", func(value sobek.Value) sobek.Value { // nolint:errcheck
+		auth := &oauth2Auth{provider: provider, realm: defaultRealm} // nolint:exhaustruct
+
+		if opts, isObj := value.(*sobek.Object); isObj {
+			auth.scopes = mod.optStrings(opts, "scope")
+			auth.audience = mod.optString(opts, "audience", "")
+			auth.realm = mod.optString(opts, "realm", defaultRealm)
+		}
+
+		return mod.authMiddleware(auth)
+	})
+
+	return obj
+}
+
+// oauth2Routes adds the endpoints of the provider to the route table. The routes are used only for tracking
+// (metrics, coverage), the requests are served by the provider.
+func oauth2Routes(table *routeTable) {
+	table.add(http.MethodGet, oidcDiscoveryPath)
+	table.add(http.MethodGet, oidcJWKSPath)
+	table.add(http.MethodPost, oauth2TokenPath)
+	table.add(http.MethodGet, oidcUserinfoPath)
+	table.add(http.MethodPost, oidcUserinfoPath)
+}
+
+// oauth2Clients parses the clients option: client ids mapped to secrets or to objects with secret and scopes.
+func (mod *Module) oauth2Clients(opts *sobek.Object) map[string]*oauth2Client {
+	clients := make(map[string]*oauth2Client)
+
+	v := opts.Get("clients")
+	if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return clients
+	}
+
+	obj := v.ToObject(mod.runtime())
+
+	for _, id := range obj.Keys() {
+		item := obj.Get(id)
+
+		if item.ExportType().Kind() == reflect.String {
+			clients[id] = &oauth2Client{secret: item.String()} // nolint:exhaustruct
+
+			continue
+		}
+
+		settings, isObj := item.(*sobek.Object)
+		if !isObj {
+			mod.throwf("client %s must be a secret or an object", errInvalidArg, id)
+		}
+
+		clients[id] = &oauth2Client{
+			secret: mod.optString(settings, "secret", ""),
+			scopes: mod.optStrings(settings, "scopes"),
+		}
+	}
+
+	return clients
+}
+
+// oauth2Users parses the users option: user names mapped to passwords or to objects with password and claims.
+// Properties of the object other than password are used as claims.
+func (mod *Module) oauth2Users(opts *sobek.Object) map[string]*oauth2User {
+	users := make(map[string]*oauth2User)
+
+	v := opts.Get("users")
+	if v == nil || sobek.IsUndefined(v) || sobek.IsNull(v) {
+		return users
+	}
+
+	obj := v.ToObject(mod.runtime())
+
+	for _, name := range obj.Keys() {
+		item := obj.Get(name)
+
+		if item.ExportType().Kind() == reflect.String {
+			users[name] = &oauth2User{password: item.String(), claims: nil}
+
+			continue
+		}
+
+		claims, isMap := item.Export().(map[string]interface{})
+		if !isMap {
+			mod.throwf("user %s must be a password or an object", errInvalidArg, name)
+		}
+
+		user := &oauth2User{password: fmt.Sprint(claims["password"]), claims: make(map[string]interface{})}
+
+		for key, value := range claims {
+			if key != "password" {
+				user.claims[key] = value
+			}
+		}
+
+		users[name] = user
+	}
+
+	return users
+}
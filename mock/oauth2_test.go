@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuth2Provider(t *testing.T) {
+	t.Parallel()
+
+	key, err := newRSAKey("k1")
+
+	assert.NoError(t, err)
+
+	provider := &oauth2Provider{
+		issuer: "https://idp.example.com/realms/test",
+		base:   "/realms/test",
+		clients: map[string]*oauth2Client{
+			"service": {secret: "s3cret", scopes: []string{"read", "write"}},
+			"app":     {secret: ""}, // nolint:exhaustruct
+		},
+		users: map[string]*oauth2User{
+			"alice": {password: "pw", claims: map[string]interface{}{"email": "alice@example.com"}},
+		},
+		scopes:   []string{"openid", "read"},
+		lifetime: time.Minute,
+		key:      key,
+		now:      time.Now,
+		refresh:  &grantStore{grants: make(map[string]*oauth2Grant)}, // nolint:exhaustruct
+	}
+
+	call := func(method string, path string, form url.Values, setup func(*http.Request)) (int, map[string]interface{}) {
+		r := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+
+		if form != nil {
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+
+		if setup != nil {
+			setup(r)
+		}
+
+		rec := httptest.NewRecorder()
+
+		provider.ServeHTTP(rec, r)
+
+		var body map[string]interface{}
+
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+		return rec.Code, body
+	}
+
+	status, doc := call(http.MethodGet, "/realms/test/.well-known/openid-configuration", nil, nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, provider.issuer, doc["issuer"])
+	assert.Equal(t, provider.issuer+"/token", doc["token_endpoint"])
+
+	status, jwks := call(http.MethodGet, "/.well-known/jwks.json", nil, nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, jwks["keys"], 1)
+
+	basic := func(r *http.Request) { r.SetBasicAuth("service", "s3cret") }
+
+	status, resp := call(http.MethodPost, "/token", url.Values{"grant_type": {"client_credentials"}}, basic)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "read write", resp["scope"])
+	assert.Nil(t, resp["refresh_token"])
+
+	token, err := parseJWT(resp["access_token"].(string)) // nolint:forcetypeassert
+
+	assert.NoError(t, err)
+	assert.NoError(t, key.verify(token))
+	assert.Equal(t, "service", token.claims["sub"])
+
+	status, resp = call(http.MethodPost, "/token", url.Values{"grant_type": {"client_credentials"}, "scope": {"admin"}}, basic)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_scope", resp["error"])
+
+	status, resp = call(http.MethodPost, "/token", url.Values{
+		"grant_type": {"client_credentials"}, "client_id": {"service"}, "client_secret": {"wrong"},
+	}, nil)
+
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "invalid_client", resp["error"])
+
+	password := url.Values{
+		"grant_type": {"password"}, "client_id": {"app"}, "username": {"alice"}, "password": {"pw"}, "scope": {"openid"},
+	}
+
+	status, resp = call(http.MethodPost, "/token", password, nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp["id_token"])
+	assert.NotEmpty(t, resp["refresh_token"])
+
+	access := resp["access_token"].(string) // nolint:forcetypeassert
+
+	status, info := call(http.MethodGet, "/userinfo", nil, func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+access) })
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"sub": "alice", "email": "alice@example.com"}, info)
+
+	refresh := url.Values{"grant_type": {"refresh_token"}, "client_id": {"app"}, "refresh_token": {resp["refresh_token"].(string)}}
+
+	status, resp = call(http.MethodPost, "/token", refresh, nil)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp["access_token"])
+
+	status, resp = call(http.MethodPost, "/token", refresh, nil)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", resp["error"])
+
+	password.Set("password", "wrong")
+
+	status, resp = call(http.MethodPost, "/token", password, nil)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", resp["error"])
+
+	status, resp = call(http.MethodPost, "/token", url.Values{"grant_type": {"implicit"}, "client_id": {"app"}}, nil)
+
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "unsupported_grant_type", resp["error"])
+
+	status, _ = call(http.MethodGet, "/authorize", nil, nil)
+
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestModuleOAuth2(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+const idp = mock.oauth2("https://idp.example.com", {
+  clients: { service: { secret: "s3cret", scopes: ["read", "write"] }, reader: { secret: "s3cret", scopes: ["read"] } },
+})
+
+mock("https://api.example.com", app => {
+  app.get("/items", idp.auth({ scope: "write" }), (req, res) => res.json([1, 2]))
+})
+`)
+
+	assert.NoError(t, err)
+
+	// the provider is served by the mock server, without application
+	assert.Contains(t, helper.module.servers, "https://idp.example.com")
+	assert.Nil(t, helper.module.apps["https://idp.example.com"])
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+const token = client => http.post(idp.issuer + "/token", { grant_type: "client_credentials", client_id: client, client_secret: "s3cret" }).json("access_token")
+const items = token => http.get("https://api.example.com/items", token ? { headers: { Authorization: "Bearer " + token } } : {}).status
+
+const discovery = http.get("https://idp.example.com/.well-known/openid-configuration").json()
+
+;[discovery.token_endpoint, items(), items("invalid"), items(token("reader")), items(token("service"))]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"https://idp.example.com/token", int64(401), int64(401), int64(403), int64(200)}, value.Export())
+
+	_, err = helper.vu.Runtime().RunString(`mock.oauth2("https://idp.example.net", { clients: { service: 42 } })`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// pipeNetwork is the network name of in-memory listeners.
+const pipeNetwork = "pipe"
+
+// pipeListener is an in-memory net.Listener. Connections are created by dial, using net.Pipe.
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})} // nolint:exhaustruct
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// dial returns the client side of a new connection, regardless of the given network and address.
+func (l *pipeListener) dial(ctx context.Context, _ string, _ string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		client.Close() // nolint:errcheck
+		server.Close() // nolint:errcheck
+
+		return nil, &net.OpError{Op: "dial", Net: pipeNetwork, Err: net.ErrClosed} // nolint:exhaustruct
+	case <-ctx.Done():
+		client.Close() // nolint:errcheck
+		server.Close() // nolint:errcheck
+
+		return nil, ctx.Err()
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string {
+	return pipeNetwork
+}
+
+func (pipeAddr) String() string {
+	return "memory"
+}
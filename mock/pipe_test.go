@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeListener(t *testing.T) {
+	t.Parallel()
+
+	listener := newPipeListener()
+
+	assert.Equal(t, pipeNetwork, listener.Addr().Network())
+	assert.Equal(t, "memory", listener.Addr().String())
+
+	accepted := make(chan net.Conn)
+
+	go func() {
+		conn, err := listener.Accept()
+
+		assert.NoError(t, err)
+
+		accepted <- conn
+	}()
+
+	client, err := listener.dial(context.Background(), "tcp", "ignored:80")
+
+	assert.NoError(t, err)
+
+	server := <-accepted
+
+	go client.Write([]byte("ping")) // nolint:errcheck
+
+	buff := make([]byte, 4)
+
+	_, err = server.Read(buff)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buff))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = listener.dial(ctx, "tcp", "")
+
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, listener.Close())
+	assert.NoError(t, listener.Close())
+
+	_, err = listener.Accept()
+
+	assert.ErrorIs(t, err, net.ErrClosed)
+
+	_, err = listener.dial(context.Background(), "tcp", "")
+
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
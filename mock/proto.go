@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Protocols of mock servers.
+const (
+	protoHTTP1 = ""
+	protoHTTPS = "https"
+	protoH2    = "h2"
+	protoH2C   = "h2c"
+)
+
+func validProto(proto string) bool {
+	return proto == protoHTTP1 || proto == protoH2 || proto == protoH2C
+}
+
+// tlsProto returns the protocol talking over TLS instead of given protocol: HTTP/1.1 over TLS
+// instead of cleartext HTTP/1.1. There is no TLS variant of h2c.
+func tlsProto(proto string) (string, bool) {
+	switch proto {
+	case protoHTTP1, protoHTTPS:
+		return protoHTTPS, true
+	case protoH2:
+		return protoH2, true
+	default:
+		return proto, false
+	}
+}
+
+// secure reports whether the protocol talks over TLS.
+func secure(proto string) bool {
+	return proto == protoHTTPS || proto == protoH2
+}
+
+// certificateValidity is the validity period of generated mock server certificates.
+const certificateValidity = 24 * time.Hour
+
+// newCertificate generates a self-signed certificate for the dial host and the loopback addresses.
+// The returned pool contains the certificate as trusted root.
+func newCertificate() (*tls.Certificate, *x509.CertPool, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64)) // nolint:gomnd
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+
+	template := &x509.Certificate{ // nolint:exhaustruct
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"xk6-mock"}}, // nolint:exhaustruct
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{dialHost, "localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}, // nolint:gomnd
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool, nil // nolint:exhaustruct
+}
+
+// newDialEntry returns the transport entry of a mock server talking given protocol over connections created by dial.
+// The roots pool is used for verifying the server certificate in case of protocols over TLS.
+func newDialEntry(dial dialFunc, proto string, roots *x509.CertPool) *dialEntry {
+	if proto == protoH2C {
+		transport := &http2.Transport{ // nolint:exhaustruct
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}
+
+		return &dialEntry{transport: transport, scheme: "http", close: transport.CloseIdleConnections}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() // nolint:forcetypeassert
+	transport.Proxy = nil
+	transport.DialContext = dial
+
+	if !secure(proto) {
+		return &dialEntry{transport: transport, scheme: "http", close: transport.CloseIdleConnections}
+	}
+
+	transport.ForceAttemptHTTP2 = proto == protoH2
+	transport.TLSClientConfig = &tls.Config{ // nolint:exhaustruct
+		RootCAs:    roots,
+		ServerName: dialHost,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return &dialEntry{transport: transport, scheme: "https", close: transport.CloseIdleConnections}
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"crypto/x509"
+	"net/http"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestValidProto(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, validProto(protoHTTP1))
+	assert.True(t, validProto(protoH2))
+	assert.True(t, validProto(protoH2C))
+	assert.False(t, validProto("h3"))
+	assert.False(t, validProto(protoHTTPS))
+}
+
+func TestTLSProto(t *testing.T) {
+	t.Parallel()
+
+	for proto, expected := range map[string]string{protoHTTP1: protoHTTPS, protoHTTPS: protoHTTPS, protoH2: protoH2} {
+		actual, ok := tlsProto(proto)
+
+		assert.True(t, ok)
+		assert.Equal(t, expected, actual)
+		assert.True(t, secure(actual))
+	}
+
+	_, ok := tlsProto(protoH2C)
+
+	assert.False(t, ok)
+	assert.False(t, secure(protoHTTP1))
+	assert.False(t, secure(protoH2C))
+}
+
+func TestNewCertificate(t *testing.T) {
+	t.Parallel()
+
+	cert, roots, err := newCertificate()
+
+	assert.NoError(t, err)
+	assert.NotNil(t, cert.PrivateKey)
+
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: dialHost, Roots: roots}) // nolint:exhaustruct
+
+	assert.NoError(t, err)
+}
+
+type protoTestSuite struct {
+	suiteBase
+}
+
+func TestProto(t *testing.T) {
+	t.Parallel()
+
+	suite.Run(t, new(protoTestSuite))
+}
+
+func (suite *protoTestSuite) SetupSuite() {
+	suite.suiteBase.SetupSuite()
+
+	suite.js(`
+const define = app => app.get("/proto", (req, res) => res.json({ ok: true }))
+
+mock("https://h2.example.com", define, { sync: true, http2: true })
+mock("https://h2c.example.com", define, { sync: true, http2: "h2c", memory: true })
+mock("https://h1.example.com", define, { sync: true })
+mock("https://tls.example.com", define, { sync: true, tls: true })
+`)
+
+	moveToHTTPContext(suite.testHelper)
+}
+
+func (suite *protoTestSuite) TestProto() {
+	value := suite.js(`
+;["h2", "h2c", "h1", "tls"].map(name => {
+  const res = http.get("https://" + name + ".example.com/proto")
+
+  return [res.status, res.proto, res.url.startsWith("https://" + name + ".example.com")]
+})
+`)
+
+	suite.Equal([]interface{}{
+		[]interface{}{int64(200), "HTTP/2.0", true},
+		[]interface{}{int64(200), "HTTP/2.0", true},
+		[]interface{}{int64(200), "HTTP/1.1", false}, // URL of HTTP/1.1 TCP mocks is rewritten
+		[]interface{}{int64(200), "HTTP/1.1", true},
+	}, value.Export())
+}
+
+func (suite *protoTestSuite) TestListener() {
+	srv := suite.module.servers["https://h2.example.com"]
+
+	suite.Contains(srv.url(), "https://127.0.0.1:")
+
+	client := req.C().EnableInsecureSkipVerify()
+
+	resp, err := client.R().Get(srv.url() + "/proto")
+
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.GetStatusCode())
+	suite.Equal("HTTP/2.0", resp.Proto)
+
+	srv = suite.module.servers["https://tls.example.com"]
+
+	suite.Contains(srv.url(), "https://127.0.0.1:")
+
+	resp, err = client.R().Get(srv.url() + "/proto")
+
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.GetStatusCode())
+	suite.Equal("HTTP/1.1", resp.Proto)
+}
+
+func (suite *protoTestSuite) TestInvalid() {
+	_, err := suite.run(`mock("https://h3.example.com", app => {}, { sync: true, http2: "h3" })`)
+
+	suite.ErrorIs(err, errInvalidArg)
+
+	_, err = suite.run(`mock("https://tls.example.net", app => {}, { sync: true, http2: "h2c", tls: true })`)
+
+	suite.ErrorIs(err, errInvalidArg)
+}
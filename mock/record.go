@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// recordingFile is the name of the file in the recording directory.
+const recordingFile = "requests.jsonl"
+
+// recorder writes the requests served by the mock servers of all VUs to the recording directory,
+// one JSON object per line.
+type recorder struct {
+	mu      sync.Mutex
+	dir     string
+	file    *os.File
+	encoder *json.Encoder
+}
+
+type recordEntry struct {
+	Time     time.Time `json:"time"`
+	Target   string    `json:"target"`
+	Method   string    `json:"method"`
+	Path     string    `json:"path"`
+	Route    string    `json:"route,omitempty"`
+	Status   int       `json:"status"`
+	Duration float64   `json:"duration"`
+}
+
+// open creates the recording file in given directory. The file is created only once (not even
+// after closing), all VUs use the same directory from k6 options.
+func (rec *recorder) open(dir string) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if len(rec.dir) != 0 {
+		if rec.dir != dir {
+			return fmt.Errorf("%w: recording directory is already %s", errInvalidArg, rec.dir)
+		}
+
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil { // nolint:gomnd
+		return err
+	}
+
+	file, err := os.Create(filepath.Clean(filepath.Join(dir, recordingFile)))
+	if err != nil {
+		return err
+	}
+
+	rec.dir, rec.file, rec.encoder = dir, file, json.NewEncoder(file)
+
+	return nil
+}
+
+// record writes the exchange to the recording file, if recording is enabled.
+func (rec *recorder) record(exc *exchange) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.file == nil {
+		return nil
+	}
+
+	entry := &recordEntry{
+		Time:     exc.start,
+		Target:   exc.target,
+		Method:   exc.method,
+		Path:     exc.path,
+		Route:    "",
+		Status:   exc.status,
+		Duration: float64(exc.duration) / float64(time.Millisecond),
+	}
+
+	if exc.route != nil {
+		entry.Route = exc.route.path
+	}
+
+	return rec.encoder.Encode(entry)
+}
+
+// close closes the recording file. Requests served after closing are not recorded.
+func (rec *recorder) close() error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.file == nil {
+		return nil
+	}
+
+	file := rec.file
+	rec.file, rec.encoder = nil, nil
+
+	return file.Close()
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	rec := new(recorder)
+	dir := filepath.Join(t.TempDir(), "recordings")
+	start := time.Now()
+
+	// nothing is recorded without recording directory
+	assert.NoError(t, rec.record(&exchange{target: "https://example.com", method: http.MethodGet, path: "/"}))
+
+	assert.NoError(t, rec.open(dir))
+	assert.NoError(t, rec.open(dir))
+	assert.ErrorIs(t, rec.open(t.TempDir()), errInvalidArg)
+
+	users := &route{method: http.MethodGet, path: "/users/:id"}
+
+	assert.NoError(t, rec.record(&exchange{
+		target:   "https://example.com",
+		method:   http.MethodGet,
+		path:     "/users/42",
+		start:    start,
+		route:    users,
+		status:   http.StatusOK,
+		duration: 1500 * time.Microsecond,
+	}))
+	assert.NoError(t, rec.record(&exchange{target: "https://example.com", method: http.MethodPost, path: "/", status: 404}))
+
+	assert.NoError(t, rec.close())
+	assert.NoError(t, rec.close())
+
+	// requests served after closing are not recorded, the file is not created again
+	assert.NoError(t, rec.record(&exchange{target: "https://example.com", method: http.MethodGet, path: "/"}))
+	assert.NoError(t, rec.open(dir))
+
+	data, err := os.ReadFile(filepath.Join(dir, recordingFile)) // nolint:gosec
+
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	assert.Len(t, lines, 2)
+
+	var entry recordEntry
+
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.True(t, start.Equal(entry.Time))
+	assert.Equal(t, "/users/:id", entry.Route)
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.InDelta(t, 1.5, entry.Duration, 0.001)
+
+	assert.NotContains(t, lines[1], `"route"`)
+}
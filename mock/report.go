@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/modules"
+)
+
+// reportAtTestEnd subscribes to k6 global events and reports unmatched requests and route coverage
+// (if coverage report file name is given in K6_MOCK_COVERAGE environment variable) when the test ends.
+// The mock servers of all VUs are shut down and the recording file is closed at exit, after handleSummary,
+// which may still send requests to the mocks.
+func (root *RootModule) reportAtTestEnd(vu modules.VU) { // nolint:varnamelen
+	events := vu.Events().Global
+	if events == nil {
+		return
+	}
+
+	var filename string
+
+	if env := vu.InitEnv(); env != nil && env.LookupEnv != nil {
+		filename, _ = env.LookupEnv(coverageEnv)
+	}
+
+	logger := newLogger(vu)
+	sid, ch := events.Subscribe(event.TestEnd, event.Exit)
+
+	go func() {
+		for evt := range ch {
+			if evt.Type == event.TestEnd {
+				root.unmatched.report(logger)
+
+				if len(filename) != 0 {
+					if err := root.coverage.write(filename); err != nil {
+						logger.WithError(err).Error("unable to write coverage report")
+					}
+				}
+
+			}
+
+			if evt.Type == event.Exit {
+				root.modules.teardown()
+
+				if err := root.recorder.close(); err != nil {
+					logger.WithError(err).Error("unable to close recording file")
+				}
+			}
+
+			if evt.Done != nil {
+				evt.Done()
+			}
+
+			if evt.Type == event.Exit {
+				events.Unsubscribe(sid)
+
+				return
+			}
+		}
+	}()
+}
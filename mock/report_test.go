@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.k6.io/k6/event"
+	"go.k6.io/k6/js/common"
+)
+
+func TestReportAtTestEnd(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	events := event.NewEventSystem(10, logrus.StandardLogger())
+	filename := filepath.Join(t.TempDir(), "coverage.json")
+
+	helper.vu.EventsField = common.Events{Global: events, Local: event.NewEventSystem(10, logrus.StandardLogger())}
+	helper.vu.InitEnvField.LookupEnv = func(key string) (string, bool) {
+		if key == coverageEnv {
+			return filename, true
+		}
+
+		return "", false
+	}
+
+	root := New().(*RootModule) // nolint:forcetypeassert
+
+	root.coverage.register("https://example.com", []*route{{method: http.MethodGet, path: "/"}})
+
+	_, err := helper.vu.Runtime().RunString(`
+const policy = mock.cors()
+
+mock("https://example.com", app => { app.use(policy) }, { sync: true })
+`)
+
+	assert.NoError(t, err)
+	assert.Len(t, helper.module.markers, 1)
+
+	root.modules.add(helper.module)
+
+	assert.NotPanics(t, func() { root.reportAtTestEnd(helper.vu) })
+
+	wait := events.Emit(&event.Event{Type: event.TestEnd}) // nolint:exhaustruct
+	assert.NoError(t, wait(context.Background()))
+
+	// handleSummary runs after the end of the test, the mocks are still serving
+	assert.Equal(t, []string{"https://example.com"}, helper.module.targets())
+
+	wait = events.Emit(&event.Event{Type: event.Exit}) // nolint:exhaustruct
+	assert.NoError(t, wait(context.Background()))
+
+	assert.Empty(t, helper.module.targets())
+
+	// the marker of the policy created outside of the mock definition is released too
+	assert.Empty(t, helper.module.markers)
+
+	assert.FileExists(t, filename)
+
+	data, err := os.ReadFile(filename)
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"target": "https://example.com"`)
+
+	helper.vu.EventsField = common.Events{} // nolint:exhaustruct
+
+	assert.NotPanics(t, func() { root.reportAtTestEnd(helper.vu) })
+}
@@ -0,0 +1,348 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/grafana/sobek"
+	"github.com/julienschmidt/httprouter"
+)
+
+type route struct {
+	method string
+	path   string
+}
+
+func (r *route) String() string {
+	return r.method + " " + r.path
+}
+
+// routeTable mirrors the routes registered in a muxpress application.
+// It uses the same router implementation, so matching gives the same result.
+type routeTable struct {
+	mu     sync.RWMutex
+	router *httprouter.Router
+	routes []*route
+	cors   *corsPolicy
+}
+
+func newRouteTable() *routeTable {
+	return &routeTable{ // nolint:exhaustruct
+		router: httprouter.New(),
+		routes: []*route{},
+	}
+}
+
+// routeRecorder is passed to the router for finding out which route was matched,
+// or how the router answers the request without route.
+type routeRecorder struct {
+	header http.Header
+	status int
+	route  *route
+}
+
+func newRouteRecorder() *routeRecorder {
+	return &routeRecorder{header: make(http.Header), status: 0, route: nil}
+}
+
+func (rec *routeRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *routeRecorder) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (rec *routeRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (table *routeTable) add(method string, path string) *route {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	rte := &route{method: method, path: path}
+
+	table.router.Handle(method, path, func(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+		if rec, ok := w.(*routeRecorder); ok {
+			rec.route = rte
+		}
+	})
+
+	table.routes = append(table.routes, rte)
+
+	return rte
+}
+
+func (table *routeTable) setCORS(policy *corsPolicy) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	table.cors = policy
+}
+
+func (table *routeTable) corsPolicy() *corsPolicy {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+
+	return table.cors
+}
+
+// match returns the route matching given method and path, or nil if there is no matching route.
+func (table *routeTable) match(method string, path string) *route {
+	return table.serve(method, path).route
+}
+
+// answers reports whether the router answers the request without matching route, like the muxpress router does:
+// redirect to the path with or without trailing slash (or to the cleaned path), or automatic OPTIONS response.
+func (table *routeTable) answers(method string, path string) bool {
+	rec := table.serve(method, path)
+
+	switch {
+	case rec.route != nil:
+		return false
+	case rec.status == http.StatusMovedPermanently, rec.status == http.StatusTemporaryRedirect:
+		return true
+	default:
+		return method == http.MethodOptions && rec.status == 0 && len(rec.header.Get("Allow")) != 0
+	}
+}
+
+// serve passes the request to the router of the table and returns the recorded result.
+func (table *routeTable) serve(method string, path string) *routeRecorder {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+
+	rec := newRouteRecorder()
+	req := &http.Request{Method: method, URL: &url.URL{Path: path}, Header: make(http.Header)} // nolint:exhaustruct
+
+	table.router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (table *routeTable) all() []*route {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+
+	return append([]*route{}, table.routes...)
+}
+
+var routeMethods = map[string]string{
+	"get":     http.MethodGet,
+	"head":    http.MethodHead,
+	"post":    http.MethodPost,
+	"put":     http.MethodPut,
+	"patch":   http.MethodPatch,
+	"delete":  http.MethodDelete,
+	"options": http.MethodOptions,
+}
+
+// trackRoutes wraps route definition methods of the application for keeping the route table in sync.
+func (mod *Module) trackRoutes(app *sobek.Object) *routeTable {
+	table := newRouteTable()
+
+	track := func(name string, register func(call sobek.FunctionCall)) {
+		original, ok := sobek.AssertFunction(app.Get(name))
+		if !ok {
+			mod.throwf("%s must be callable", errInvalidArg, name)
+		}
+
+		wrapper := func(call sobek.FunctionCall) sobek.Value {
+			v, err := original(app, call.Arguments...)
+			if err != nil {
+				mod.throw(err)
+			}
+
+			register(call)
+
+			return v
+		}
+
+		if err := app.Set(name, wrapper); err != nil {
+			mod.throw(err)
+		}
+	}
+
+	for name, method := range routeMethods {
+		method := method
+
+		track(name, func(call sobek.FunctionCall) {
+			table.add(method, call.Argument(0).String())
+		})
+	}
+
+	track("static", func(call sobek.FunctionCall) {
+		table.add(http.MethodGet, staticPath(call.Argument(0).String()))
+	})
+
+	track("use", func(call sobek.FunctionCall) {
+		for _, arg := range call.Arguments {
+			if policy := mod.marked(arg).cors; policy != nil {
+				table.setCORS(policy)
+			}
+		}
+	})
+
+	return table
+}
+
+// staticPath returns the route path used by muxpress for static content.
+func staticPath(path string) string {
+	if strings.HasSuffix(path, "/*filepath") {
+		return path
+	}
+
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	return path + "*filepath"
+}
+
+// maxNearMisses is the maximum number of near-miss routes reported for an unmatched request.
+const maxNearMisses = 3
+
+// nearMiss is a route which almost matched a request.
+type nearMiss struct {
+	route  *route
+	reason string
+}
+
+func (miss *nearMiss) String() string {
+	return miss.route.String() + " (" + miss.reason + ")"
+}
+
+// nearest returns the routes which almost matched given method and path.
+// Routes accepting the path with another method come first.
+func (table *routeTable) nearest(method string, path string) []*nearMiss {
+	var methodMisses, pathMisses []*nearMiss
+
+	for _, rte := range table.all() {
+		reason, methodOnly := rte.miss(method, path)
+
+		switch {
+		case len(reason) == 0:
+			continue
+		case methodOnly:
+			methodMisses = append(methodMisses, &nearMiss{route: rte, reason: reason})
+		default:
+			pathMisses = append(pathMisses, &nearMiss{route: rte, reason: reason})
+		}
+	}
+
+	misses := append(methodMisses, pathMisses...)
+	if len(misses) > maxNearMisses {
+		misses = misses[:maxNearMisses]
+	}
+
+	return misses
+}
+
+// miss explains why the route did not match given method and path.
+// Empty string is returned if the route is not close to the request.
+// The methodOnly flag is true if only the method differs.
+func (r *route) miss(method string, path string) (string, bool) {
+	want := splitPath(r.path)
+	got := splitPath(path)
+
+	if last := len(want) - 1; strings.HasPrefix(want[last], "*") && len(got) > last {
+		got = append(got[:last], strings.Join(got[last:], "/"))
+	}
+
+	diff, count, literals := -1, 0, 0
+
+	for idx := 0; idx < len(want) && idx < len(got); idx++ {
+		switch {
+		case !segmentMatch(want[idx], got[idx]):
+			count++
+
+			if diff < 0 {
+				diff = idx
+			}
+		case !isParam(want[idx]):
+			literals++
+		}
+	}
+
+	sameMethod := r.method == method
+
+	switch {
+	case len(want) == len(got) && count == 0 && !sameMethod:
+		return "method mismatch, route accepts " + r.method, true
+	case len(want) == len(got) && count == 0 && strings.HasSuffix(r.path, "/") != strings.HasSuffix(path, "/"):
+		return "trailing slash mismatch", false
+	case !sameMethod:
+		return "", false
+	case len(want) == len(got) && count == 1 && (literals != 0 || distance(want[diff], got[diff]) <= maxSegmentDistance):
+		return fmt.Sprintf("path differs in segment %d, expected %q, got %q", diff+1, want[diff], got[diff]), false
+	case count == 0 && len(got) == len(want)+1:
+		return fmt.Sprintf("path has an extra segment %q", got[len(want)]), false
+	case count == 0 && len(want) == len(got)+1:
+		return fmt.Sprintf("path is missing segment %q", want[len(got)]), false
+	}
+
+	return "", false
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")
+}
+
+func segmentMatch(pattern string, segment string) bool {
+	if isParam(pattern) {
+		return len(segment) != 0
+	}
+
+	return pattern == segment
+}
+
+// maxSegmentDistance is the maximum edit distance of a mistyped path segment.
+const maxSegmentDistance = 2
+
+// distance returns the Levenshtein distance of two strings.
+func distance(from string, to string) int {
+	src, dst := []rune(from), []rune(to)
+	prev := make([]int, len(dst)+1)
+	curr := make([]int, len(dst)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(src); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(dst); j++ {
+			cost := 1
+			if src[i-1] == dst[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	return prev[len(dst)]
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTable(t *testing.T) {
+	t.Parallel()
+
+	table := newRouteTable()
+
+	table.add(http.MethodGet, "/users/:id")
+	table.add(http.MethodPost, "/users")
+	table.add(http.MethodGet, staticPath("/assets"))
+
+	assert.Len(t, table.all(), 3)
+
+	assert.Equal(t, "GET /users/:id", table.match(http.MethodGet, "/users/42").String())
+	assert.Equal(t, "POST /users", table.match(http.MethodPost, "/users").String())
+	assert.Equal(t, "GET /assets/*filepath", table.match(http.MethodGet, "/assets/app.js").String())
+
+	assert.Nil(t, table.match(http.MethodDelete, "/users/42"))
+	assert.Nil(t, table.match(http.MethodGet, "/users/42/orders"))
+	assert.Nil(t, table.match(http.MethodGet, "/"))
+}
+
+func TestRouteTableAnswers(t *testing.T) {
+	t.Parallel()
+
+	table := newRouteTable()
+
+	table.add(http.MethodGet, "/users/:id")
+	table.add(http.MethodPost, "/users")
+
+	// redirected to the path without or with trailing slash, or to the cleaned path
+	assert.Nil(t, table.match(http.MethodGet, "/users/42/"))
+	assert.True(t, table.answers(http.MethodGet, "/users/42/"))
+	assert.True(t, table.answers(http.MethodPost, "/users/"))
+	assert.True(t, table.answers(http.MethodGet, "/USERS/42"))
+
+	// automatic OPTIONS response
+	assert.Nil(t, table.match(http.MethodOptions, "/users"))
+	assert.True(t, table.answers(http.MethodOptions, "/users"))
+
+	assert.False(t, table.answers(http.MethodGet, "/users/42"))
+	assert.False(t, table.answers(http.MethodOptions, "/orders"))
+	assert.False(t, table.answers(http.MethodDelete, "/users/42"))
+	assert.False(t, table.answers(http.MethodGet, "/orders"))
+}
+
+func TestStaticPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "/*filepath", staticPath("/"))
+	assert.Equal(t, "/public/*filepath", staticPath("/public"))
+	assert.Equal(t, "/public/*filepath", staticPath("/public/"))
+	assert.Equal(t, "/public/*filepath", staticPath("/public/*filepath"))
+}
+
+func TestTrackRoutes(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	app, _ := helper.module.newApplication(new(appSettings))
+	table := helper.module.trackRoutes(app)
+
+	assert.NoError(t, helper.vu.Runtime().Set("app", app))
+
+	_, err := helper.vu.Runtime().RunString(`
+app.get("/", (req, res) => res.text("root"))
+app.delete("/items/:id", (req, res) => res.status(204))
+app.static("/public", "/tmp")
+`)
+
+	assert.NoError(t, err)
+
+	assert.Len(t, table.all(), 3)
+	assert.NotNil(t, table.match(http.MethodGet, "/"))
+	assert.NotNil(t, table.match(http.MethodDelete, "/items/1"))
+	assert.NotNil(t, table.match(http.MethodGet, "/public/index.html"))
+}
+
+func TestRouteTableNearest(t *testing.T) {
+	t.Parallel()
+
+	table := newRouteTable()
+
+	table.add(http.MethodGet, "/users/:id")
+	table.add(http.MethodPost, "/users")
+	table.add(http.MethodGet, "/orders/")
+	table.add(http.MethodGet, staticPath("/assets"))
+
+	reasons := func(method, path string) []string {
+		var all []string
+
+		for _, miss := range table.nearest(method, path) {
+			all = append(all, miss.String())
+		}
+
+		return all
+	}
+
+	assert.Equal(t, []string{
+		"POST /users (method mismatch, route accepts POST)",
+		`GET /users/:id (path is missing segment ":id")`,
+	}, reasons(http.MethodGet, "/users"))
+	assert.Equal(t, []string{"GET /users/:id (method mismatch, route accepts GET)"}, reasons(http.MethodPut, "/users/42"))
+	assert.Equal(t, []string{`GET /users/:id (path differs in segment 1, expected "users", got "user")`}, reasons(http.MethodGet, "/user/42"))
+	assert.Equal(t, []string{`GET /users/:id (path has an extra segment "orders")`}, reasons(http.MethodGet, "/users/42/orders"))
+	assert.Equal(t, []string{"GET /orders/ (trailing slash mismatch)"}, reasons(http.MethodGet, "/orders"))
+	assert.Equal(t, []string{`GET /assets/*filepath (path is missing segment "*filepath")`}, reasons(http.MethodGet, "/assets"))
+	assert.Empty(t, reasons(http.MethodGet, "/foo/bar/baz"))
+
+	for i := 0; i < 5; i++ {
+		table.add(http.MethodGet, "/items"+strconv.Itoa(i))
+	}
+
+	assert.Len(t, table.nearest(http.MethodGet, "/items9"), maxNearMisses)
+}
+
+func TestDistance(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, distance("users", "users"))
+	assert.Equal(t, 1, distance("users", "user"))
+	assert.Equal(t, 2, distance("orders", "odrers"))
+	assert.Equal(t, 3, distance("", "abc"))
+	assert.Equal(t, 4, distance("orders", "assets"))
+}
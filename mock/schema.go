@@ -0,0 +1,732 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// schema is a JSON Schema (draft 2020-12) validator.
+//
+// Only local references (`#`, `#/$defs/...`) are supported. Unlike the specification default,
+// the most common `format` values are asserted, not only annotated. Schemas using keywords of
+// the specification not implemented here are rejected, they would pass invalid instances.
+type schema struct {
+	root interface{}
+
+	mu      sync.Mutex
+	regexps map[string]*regexp.Regexp
+}
+
+type schemaError struct {
+	KeywordLocation  string `json:"keywordLocation"`
+	InstanceLocation string `json:"instanceLocation"`
+	Error            string `json:"error"`
+}
+
+var errInvalidSchema = errors.New("invalid schema")
+
+// unsupportedKeywords are the keywords of draft 2020-12 not implemented by the validator,
+// and the keywords of earlier drafts replaced by other keywords in draft 2020-12.
+var unsupportedKeywords = []string{
+	"unevaluatedProperties", "unevaluatedItems",
+	"$anchor", "$dynamicAnchor", "$dynamicRef", "$recursiveAnchor", "$recursiveRef",
+	"additionalItems", "dependencies",
+}
+
+func newSchema(from interface{}) (*schema, error) {
+	root := normalizeJSON(from)
+
+	switch root.(type) {
+	case bool, map[string]interface{}:
+	default:
+		return nil, fmt.Errorf("%w: schema must be an object or boolean", errInvalidSchema)
+	}
+
+	sch := &schema{root: root, regexps: make(map[string]*regexp.Regexp)} // nolint:exhaustruct
+
+	if err := sch.check(root, false); err != nil {
+		return nil, err
+	}
+
+	return sch, nil
+}
+
+func parseSchema(data []byte) (*schema, error) {
+	var from interface{}
+
+	if err := json.Unmarshal(data, &from); err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidSchema, err.Error())
+	}
+
+	return newSchema(from)
+}
+
+// check compiles regular expressions and resolves references in advance, to report schema errors early.
+// Unsupported keywords and embedded schema resources (subschemas with $id) are rejected too,
+// references are resolved against the root schema only.
+func (sch *schema) check(node interface{}, nested bool) error {
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		if _, isBool := node.(bool); !isBool {
+			return fmt.Errorf("%w: subschema must be an object or boolean", errInvalidSchema)
+		}
+
+		return nil
+	}
+
+	for _, keyword := range unsupportedKeywords {
+		if _, found := obj[keyword]; found {
+			return fmt.Errorf("%w: unsupported keyword: %s", errInvalidSchema, keyword)
+		}
+	}
+
+	if _, found := obj["$id"]; found && nested {
+		return fmt.Errorf("%w: unsupported $id in subschema", errInvalidSchema)
+	}
+
+	if pattern, ok := obj["pattern"].(string); ok {
+		if _, err := sch.regexp(pattern); err != nil {
+			return fmt.Errorf("%w: %s", errInvalidSchema, err.Error())
+		}
+	}
+
+	if ref, ok := obj["$ref"].(string); ok {
+		if _, err := sch.resolve(ref); err != nil {
+			return err
+		}
+	}
+
+	subschemas := []interface{}{}
+
+	for _, key := range []string{"items", "contains", "not", "if", "then", "else", "additionalProperties", "propertyNames"} {
+		if sub, found := obj[key]; found {
+			subschemas = append(subschemas, sub)
+		}
+	}
+
+	for _, key := range []string{"allOf", "anyOf", "oneOf", "prefixItems"} {
+		if list, ok := obj[key].([]interface{}); ok {
+			subschemas = append(subschemas, list...)
+		}
+	}
+
+	for _, key := range []string{"properties", "patternProperties", "$defs", "definitions", "dependentSchemas"} {
+		all, _ := obj[key].(map[string]interface{})
+
+		for name, sub := range all {
+			if key == "patternProperties" {
+				if _, err := sch.regexp(name); err != nil {
+					return fmt.Errorf("%w: %s", errInvalidSchema, err.Error())
+				}
+			}
+
+			subschemas = append(subschemas, sub)
+		}
+	}
+
+	for _, sub := range subschemas {
+		if err := sch.check(sub, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sch *schema) regexp(pattern string) (*regexp.Regexp, error) {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+
+	if re, found := sch.regexps[pattern]; found {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	sch.regexps[pattern] = re
+
+	return re, nil
+}
+
+func (sch *schema) resolve(ref string) (interface{}, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("%w: unsupported $ref: %s", errInvalidSchema, ref)
+	}
+
+	node := sch.root
+
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		if unescaped, err := url.PathUnescape(token); err == nil {
+			token = unescaped
+		}
+
+		switch val := node.(type) {
+		case map[string]interface{}:
+			node = val[token]
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(val) {
+				node = nil
+			} else {
+				node = val[idx]
+			}
+		default:
+			node = nil
+		}
+
+		if node == nil {
+			return nil, fmt.Errorf("%w: unresolvable $ref: %s", errInvalidSchema, ref)
+		}
+	}
+
+	return node, nil
+}
+
+// validate returns the list of validation errors, empty list means valid instance.
+func (sch *schema) validate(instance interface{}) []schemaError {
+	run := &validation{schema: sch, errors: []schemaError{}}
+
+	run.eval(sch.root, normalizeJSON(instance), "", "")
+
+	return run.errors
+}
+
+type validation struct {
+	schema *schema
+	errors []schemaError
+}
+
+func (v *validation) fail(kwloc, instloc string, format string, args ...interface{}) bool {
+	v.errors = append(v.errors, schemaError{
+		KeywordLocation:  kwloc,
+		InstanceLocation: instloc,
+		Error:            fmt.Sprintf(format, args...),
+	})
+
+	return false
+}
+
+// probe evaluates a subschema without collecting its errors.
+func (v *validation) probe(node interface{}, instance interface{}, kwloc, instloc string) bool {
+	saved := v.errors
+
+	v.errors = []schemaError{}
+	valid := v.eval(node, instance, kwloc, instloc)
+	v.errors = saved
+
+	return valid
+}
+
+func (v *validation) eval(node interface{}, instance interface{}, kwloc, instloc string) bool {
+	switch sch := node.(type) {
+	case bool:
+		if !sch {
+			return v.fail(kwloc, instloc, "false schema does not allow any value")
+		}
+
+		return true
+	case map[string]interface{}:
+		return v.evalObject(sch, instance, kwloc, instloc)
+	default:
+		return true
+	}
+}
+
+func (v *validation) evalObject(sch map[string]interface{}, instance interface{}, kwloc, instloc string) bool {
+	valid := true
+
+	if ref, ok := sch["$ref"].(string); ok {
+		target, err := v.schema.resolve(ref)
+		if err != nil {
+			return v.fail(kwloc+"/$ref", instloc, "%s", err.Error())
+		}
+
+		valid = v.eval(target, instance, kwloc+"/$ref", instloc) && valid
+	}
+
+	valid = v.evalGeneric(sch, instance, kwloc, instloc) && valid
+	valid = v.evalComposition(sch, instance, kwloc, instloc) && valid
+
+	switch val := instance.(type) {
+	case float64:
+		valid = v.evalNumber(sch, val, kwloc, instloc) && valid
+	case string:
+		valid = v.evalString(sch, val, kwloc, instloc) && valid
+	case []interface{}:
+		valid = v.evalArray(sch, val, kwloc, instloc) && valid
+	case map[string]interface{}:
+		valid = v.evalProperties(sch, val, kwloc, instloc) && valid
+	}
+
+	return valid
+}
+
+func (v *validation) evalGeneric(sch map[string]interface{}, instance interface{}, kwloc, instloc string) bool {
+	valid := true
+
+	if typ, found := sch["type"]; found {
+		types := []string{}
+
+		switch val := typ.(type) {
+		case string:
+			types = append(types, val)
+		case []interface{}:
+			for _, t := range val {
+				types = append(types, fmt.Sprint(t))
+			}
+		}
+
+		matched := false
+
+		for _, t := range types {
+			if jsonTypeMatches(t, instance) {
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			valid = v.fail(kwloc+"/type", instloc, "expected %s, but got %s", strings.Join(types, " or "), jsonTypeOf(instance))
+		}
+	}
+
+	if enum, ok := sch["enum"].([]interface{}); ok {
+		found := false
+
+		for _, item := range enum {
+			if reflect.DeepEqual(item, instance) {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			valid = v.fail(kwloc+"/enum", instloc, "value must be one of the enumerated values")
+		}
+	}
+
+	if constant, found := sch["const"]; found && !reflect.DeepEqual(constant, instance) {
+		valid = v.fail(kwloc+"/const", instloc, "value must be %s", toJSON(constant))
+	}
+
+	return valid
+}
+
+func (v *validation) evalComposition(sch map[string]interface{}, instance interface{}, kwloc, instloc string) bool {
+	valid := true
+
+	if all, ok := sch["allOf"].([]interface{}); ok {
+		for idx, sub := range all {
+			valid = v.eval(sub, instance, kwloc+"/allOf/"+strconv.Itoa(idx), instloc) && valid
+		}
+	}
+
+	if anyOf, ok := sch["anyOf"].([]interface{}); ok {
+		matched := false
+
+		for idx, sub := range anyOf {
+			if v.probe(sub, instance, kwloc+"/anyOf/"+strconv.Itoa(idx), instloc) {
+				matched = true
+			}
+		}
+
+		if !matched {
+			valid = v.fail(kwloc+"/anyOf", instloc, "value must match at least one schema")
+		}
+	}
+
+	if oneOf, ok := sch["oneOf"].([]interface{}); ok {
+		count := 0
+
+		for idx, sub := range oneOf {
+			if v.probe(sub, instance, kwloc+"/oneOf/"+strconv.Itoa(idx), instloc) {
+				count++
+			}
+		}
+
+		if count != 1 {
+			valid = v.fail(kwloc+"/oneOf", instloc, "value must match exactly one schema, but matched %d", count)
+		}
+	}
+
+	if not, found := sch["not"]; found && v.probe(not, instance, kwloc+"/not", instloc) {
+		valid = v.fail(kwloc+"/not", instloc, "value must not match the schema")
+	}
+
+	if cond, found := sch["if"]; found {
+		if v.probe(cond, instance, kwloc+"/if", instloc) {
+			if then, found := sch["then"]; found {
+				valid = v.eval(then, instance, kwloc+"/then", instloc) && valid
+			}
+		} else if otherwise, found := sch["else"]; found {
+			valid = v.eval(otherwise, instance, kwloc+"/else", instloc) && valid
+		}
+	}
+
+	return valid
+}
+
+func (v *validation) evalNumber(sch map[string]interface{}, num float64, kwloc, instloc string) bool {
+	valid := true
+
+	if limit, ok := sch["minimum"].(float64); ok && num < limit {
+		valid = v.fail(kwloc+"/minimum", instloc, "must be >= %v", limit)
+	}
+
+	if limit, ok := sch["maximum"].(float64); ok && num > limit {
+		valid = v.fail(kwloc+"/maximum", instloc, "must be <= %v", limit)
+	}
+
+	if limit, ok := sch["exclusiveMinimum"].(float64); ok && num <= limit {
+		valid = v.fail(kwloc+"/exclusiveMinimum", instloc, "must be > %v", limit)
+	}
+
+	if limit, ok := sch["exclusiveMaximum"].(float64); ok && num >= limit {
+		valid = v.fail(kwloc+"/exclusiveMaximum", instloc, "must be < %v", limit)
+	}
+
+	if div, ok := sch["multipleOf"].(float64); ok && div > 0 {
+		if q := num / div; math.Abs(q-math.Round(q)) > 1e-9 {
+			valid = v.fail(kwloc+"/multipleOf", instloc, "must be multiple of %v", div)
+		}
+	}
+
+	return valid
+}
+
+func (v *validation) evalString(sch map[string]interface{}, str string, kwloc, instloc string) bool {
+	valid := true
+	length := float64(utf8.RuneCountInString(str))
+
+	if limit, ok := sch["minLength"].(float64); ok && length < limit {
+		valid = v.fail(kwloc+"/minLength", instloc, "length must be >= %v", limit)
+	}
+
+	if limit, ok := sch["maxLength"].(float64); ok && length > limit {
+		valid = v.fail(kwloc+"/maxLength", instloc, "length must be <= %v", limit)
+	}
+
+	if pattern, ok := sch["pattern"].(string); ok {
+		if re, err := v.schema.regexp(pattern); err == nil && !re.MatchString(str) {
+			valid = v.fail(kwloc+"/pattern", instloc, "does not match pattern %q", pattern)
+		}
+	}
+
+	if format, ok := sch["format"].(string); ok && !formatMatches(format, str) {
+		valid = v.fail(kwloc+"/format", instloc, "is not valid %s", format)
+	}
+
+	return valid
+}
+
+func (v *validation) evalArray(sch map[string]interface{}, arr []interface{}, kwloc, instloc string) bool {
+	valid := true
+	length := float64(len(arr))
+
+	if limit, ok := sch["minItems"].(float64); ok && length < limit {
+		valid = v.fail(kwloc+"/minItems", instloc, "must have at least %v items", limit)
+	}
+
+	if limit, ok := sch["maxItems"].(float64); ok && length > limit {
+		valid = v.fail(kwloc+"/maxItems", instloc, "must have at most %v items", limit)
+	}
+
+	if unique, ok := sch["uniqueItems"].(bool); ok && unique {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if reflect.DeepEqual(arr[i], arr[j]) {
+					valid = v.fail(kwloc+"/uniqueItems", instloc, "items at %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+
+	prefix, _ := sch["prefixItems"].([]interface{})
+
+	for idx, sub := range prefix {
+		if idx < len(arr) {
+			valid = v.eval(sub, arr[idx], kwloc+"/prefixItems/"+strconv.Itoa(idx), instloc+"/"+strconv.Itoa(idx)) && valid
+		}
+	}
+
+	if items, found := sch["items"]; found {
+		for idx := len(prefix); idx < len(arr); idx++ {
+			valid = v.eval(items, arr[idx], kwloc+"/items", instloc+"/"+strconv.Itoa(idx)) && valid
+		}
+	}
+
+	if contains, found := sch["contains"]; found {
+		valid = v.evalContains(sch, contains, arr, kwloc, instloc) && valid
+	}
+
+	return valid
+}
+
+func (v *validation) evalContains(sch map[string]interface{}, contains interface{}, arr []interface{}, kwloc, instloc string) bool {
+	count := 0.0
+
+	for idx, item := range arr {
+		if v.probe(contains, item, kwloc+"/contains", instloc+"/"+strconv.Itoa(idx)) {
+			count++
+		}
+	}
+
+	minimum := 1.0
+	if limit, ok := sch["minContains"].(float64); ok {
+		minimum = limit
+	}
+
+	if count < minimum {
+		return v.fail(kwloc+"/contains", instloc, "must contain at least %v matching items", minimum)
+	}
+
+	if limit, ok := sch["maxContains"].(float64); ok && count > limit {
+		return v.fail(kwloc+"/maxContains", instloc, "must contain at most %v matching items", limit)
+	}
+
+	return true
+}
+
+func (v *validation) evalProperties(sch map[string]interface{}, obj map[string]interface{}, kwloc, instloc string) bool {
+	valid := true
+	count := float64(len(obj))
+
+	if limit, ok := sch["minProperties"].(float64); ok && count < limit {
+		valid = v.fail(kwloc+"/minProperties", instloc, "must have at least %v properties", limit)
+	}
+
+	if limit, ok := sch["maxProperties"].(float64); ok && count > limit {
+		valid = v.fail(kwloc+"/maxProperties", instloc, "must have at most %v properties", limit)
+	}
+
+	if required, ok := sch["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, found := obj[fmt.Sprint(name)]; !found {
+				valid = v.fail(kwloc+"/required", instloc, "missing property %q", name)
+			}
+		}
+	}
+
+	if deps, ok := sch["dependentRequired"].(map[string]interface{}); ok {
+		for name, list := range deps {
+			if _, found := obj[name]; !found {
+				continue
+			}
+
+			names, _ := list.([]interface{})
+
+			for _, dep := range names {
+				if _, found := obj[fmt.Sprint(dep)]; !found {
+					valid = v.fail(kwloc+"/dependentRequired/"+name, instloc, "missing property %q required by %q", dep, name)
+				}
+			}
+		}
+	}
+
+	if deps, ok := sch["dependentSchemas"].(map[string]interface{}); ok {
+		for _, name := range sortedKeys(deps) {
+			if _, found := obj[name]; found {
+				valid = v.eval(deps[name], obj, kwloc+"/dependentSchemas/"+pointerEscape(name), instloc) && valid
+			}
+		}
+	}
+
+	if names, found := sch["propertyNames"]; found {
+		for _, key := range sortedKeys(obj) {
+			valid = v.eval(names, key, kwloc+"/propertyNames", instloc+"/"+pointerEscape(key)) && valid
+		}
+	}
+
+	props, _ := sch["properties"].(map[string]interface{})
+	patterns, _ := sch["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := sch["additionalProperties"]
+
+	for _, key := range sortedKeys(obj) {
+		value := obj[key]
+		loc := instloc + "/" + pointerEscape(key)
+		evaluated := false
+
+		if sub, found := props[key]; found {
+			evaluated = true
+			valid = v.eval(sub, value, kwloc+"/properties/"+pointerEscape(key), loc) && valid
+		}
+
+		for pattern, sub := range patterns {
+			if re, err := v.schema.regexp(pattern); err == nil && re.MatchString(key) {
+				evaluated = true
+				valid = v.eval(sub, value, kwloc+"/patternProperties/"+pointerEscape(pattern), loc) && valid
+			}
+		}
+
+		if !evaluated && hasAdditional {
+			valid = v.eval(additional, value, kwloc+"/additionalProperties", loc) && valid
+		}
+	}
+
+	return valid
+}
+
+func jsonTypeOf(instance interface{}) string {
+	switch val := instance.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", instance)
+	}
+}
+
+func jsonTypeMatches(typ string, instance interface{}) bool {
+	actual := jsonTypeOf(instance)
+
+	return actual == typ || (typ == "number" && actual == "integer")
+}
+
+var (
+	uuidRE     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnameRE = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+func formatMatches(format string, str string) bool {
+	var err error
+
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339Nano, str)
+	case "date":
+		_, err = time.Parse("2006-01-02", str)
+	case "time":
+		_, err = time.Parse("15:04:05Z07:00", str)
+	case "email":
+		var addr *mail.Address
+
+		addr, err = mail.ParseAddress(str)
+		if err == nil && addr.Address != str {
+			return false
+		}
+	case "uuid":
+		return uuidRE.MatchString(str)
+	case "uri":
+		var loc *url.URL
+
+		loc, err = url.Parse(str)
+		if err == nil && !loc.IsAbs() {
+			return false
+		}
+	case "ipv4":
+		ip := net.ParseIP(str)
+
+		return ip != nil && ip.To4() != nil && strings.Contains(str, ".")
+	case "ipv6":
+		ip := net.ParseIP(str)
+
+		return ip != nil && strings.Contains(str, ":")
+	case "hostname":
+		return len(str) <= 253 && hostnameRE.MatchString(str) // nolint:gomnd
+	case "regex":
+		_, err = regexp.Compile(str)
+	}
+
+	return err == nil
+}
+
+// normalizeJSON converts exported JavaScript values to the types produced by encoding/json.
+func normalizeJSON(value interface{}) interface{} {
+	switch val := value.(type) {
+	case nil, bool, string, float64:
+		return val
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+
+		for k, v := range val {
+			out[k] = normalizeJSON(v)
+		}
+
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+
+		for i, v := range val {
+			out[i] = normalizeJSON(v)
+		}
+
+		return out
+	case int64:
+		return float64(val)
+	case int:
+		return float64(val)
+	case float32:
+		return float64(val)
+	default:
+		var out interface{}
+
+		data, err := json.Marshal(val)
+		if err != nil || json.Unmarshal(data, &out) != nil {
+			return val
+		}
+
+		return out
+	}
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+
+	for key := range obj {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func pointerEscape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func toJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
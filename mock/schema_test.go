@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSchema(t *testing.T) {
+	t.Parallel()
+
+	_, err := newSchema("string")
+	assert.ErrorIs(t, err, errInvalidSchema)
+
+	_, err = newSchema(map[string]interface{}{"pattern": "("})
+	assert.ErrorIs(t, err, errInvalidSchema)
+
+	_, err = newSchema(map[string]interface{}{"$ref": "https://example.com/schema.json"})
+	assert.ErrorIs(t, err, errInvalidSchema)
+
+	_, err = newSchema(map[string]interface{}{"$ref": "#/$defs/missing"})
+	assert.ErrorIs(t, err, errInvalidSchema)
+
+	_, err = parseSchema([]byte("{"))
+	assert.ErrorIs(t, err, errInvalidSchema)
+
+	_, err = newSchema(map[string]interface{}{"properties": map[string]interface{}{"pattern": map[string]interface{}{}}})
+	assert.NoError(t, err)
+
+	_, err = newSchema(true)
+	assert.NoError(t, err)
+
+	_, err = parseSchema([]byte(`{"$id":"https://example.com/user.json","$defs":{"name":{"type":"string"}},"$ref":"#/$defs/name"}`))
+	assert.NoError(t, err)
+}
+
+func TestNewSchemaUnsupported(t *testing.T) {
+	t.Parallel()
+
+	for _, data := range []string{
+		`{"unevaluatedProperties":false}`,
+		`{"properties":{"tags":{"unevaluatedItems":false}}}`,
+		`{"$defs":{"name":{"$anchor":"name"}}}`,
+		`{"$dynamicAnchor":"node"}`,
+		`{"items":{"$dynamicRef":"#node"}}`,
+		`{"$recursiveRef":"#"}`,
+		`{"additionalItems":false}`,
+		`{"dependencies":{"email":["name"]}}`,
+		`{"$defs":{"name":{"$id":"name.json","type":"string"}}}`,
+		`{"$ref":"name.json"}`,
+		`{"items":[{"type":"string"}]}`,
+		`{"allOf":[1]}`,
+	} {
+		_, err := parseSchema([]byte(data))
+
+		assert.ErrorIs(t, err, errInvalidSchema, data)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) { // nolint:funlen
+	t.Parallel()
+
+	sch, err := parseSchema([]byte(`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "required": ["name", "age"],
+  "additionalProperties": false,
+  "properties": {
+    "name": { "type": "string", "minLength": 2, "maxLength": 10, "pattern": "^[A-Z]" },
+    "age": { "type": "integer", "minimum": 0, "exclusiveMaximum": 150 },
+    "email": { "type": "string", "format": "email" },
+    "role": { "enum": ["admin", "user"] },
+    "tags": { "type": "array", "items": { "type": "string" }, "uniqueItems": true, "maxItems": 3 },
+    "point": { "type": "array", "prefixItems": [{ "type": "number" }, { "type": "number" }], "items": false },
+    "address": { "$ref": "#/$defs/address" },
+    "id": { "oneOf": [{ "type": "integer" }, { "type": "string", "format": "uuid" }] },
+    "score": { "type": "number", "multipleOf": 0.5 },
+    "kind": { "const": "person" },
+    "nickname": { "type": "string" }
+  },
+  "dependentRequired": { "email": ["name"] },
+  "dependentSchemas": { "nickname": { "required": ["email"] } },
+  "$defs": {
+    "address": {
+      "type": "object",
+      "required": ["city"],
+      "properties": { "city": { "type": "string" } }
+    }
+  }
+}`))
+
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		instance interface{}
+		errors   []string
+	}{
+		{name: "valid", instance: map[string]interface{}{"name": "Alice", "age": int64(30)}},
+		{
+			name: "valid full",
+			instance: map[string]interface{}{
+				"name": "Bob", "age": 2.0, "email": "bob@example.com", "role": "admin",
+				"tags": []interface{}{"a", "b"}, "point": []interface{}{1, 2.5}, "address": map[string]interface{}{"city": "X"},
+				"id": "4b7e5f3c-8d92-4f55-a0c2-2b9a5e4f1d3a", "score": 1.5, "kind": "person",
+			},
+		},
+		{name: "null", instance: nil, errors: []string{"/type"}},
+		{name: "missing", instance: map[string]interface{}{}, errors: []string{"/required", "/required"}},
+		{
+			name:     "property types",
+			instance: map[string]interface{}{"name": 1, "age": 1.5},
+			errors:   []string{"/properties/age/type", "/properties/name/type"},
+		},
+		{
+			name:     "string constraints",
+			instance: map[string]interface{}{"name": "bob", "age": 1, "email": "not an email"},
+			errors:   []string{"/properties/email/format", "/properties/name/pattern"},
+		},
+		{
+			name:     "number constraints",
+			instance: map[string]interface{}{"name": "Bob", "age": 150, "score": 0.3},
+			errors:   []string{"/properties/age/exclusiveMaximum", "/properties/score/multipleOf"},
+		},
+		{
+			name:     "additional",
+			instance: map[string]interface{}{"name": "Bob", "age": 1, "foo": true},
+			errors:   []string{"/additionalProperties"},
+		},
+		{
+			name:     "enum and const",
+			instance: map[string]interface{}{"name": "Bob", "age": 1, "role": "root", "kind": "robot"},
+			errors:   []string{"/properties/kind/const", "/properties/role/enum"},
+		},
+		{
+			name:     "arrays",
+			instance: map[string]interface{}{"name": "Bob", "age": 1, "tags": []interface{}{"a", "a", 1, "b"}, "point": []interface{}{1, 2, 3}},
+			errors:   []string{"/properties/point/items", "/properties/tags/items/type", "/properties/tags/maxItems", "/properties/tags/uniqueItems"},
+		},
+		{
+			name:     "ref",
+			instance: map[string]interface{}{"name": "Bob", "age": 1, "address": map[string]interface{}{}},
+			errors:   []string{"/properties/address/$ref/required"},
+		},
+		{
+			name:     "dependent schemas",
+			instance: map[string]interface{}{"name": "Bob", "age": 1, "nickname": "bobby"},
+			errors:   []string{"/dependentSchemas/nickname/required"},
+		},
+		{
+			name:     "oneOf",
+			instance: map[string]interface{}{"name": "Bob", "age": 1, "id": "foo"},
+			errors:   []string{"/properties/id/oneOf"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			errs := sch.validate(tt.instance)
+			locations := []string{}
+
+			for _, e := range errs {
+				locations = append(locations, e.KeywordLocation)
+			}
+
+			assert.ElementsMatch(t, tt.errors, locations)
+		})
+	}
+}
+
+func TestSchemaComposition(t *testing.T) {
+	t.Parallel()
+
+	sch, err := newSchema(map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "integer"},
+		},
+		"not": map[string]interface{}{"const": "forbidden"},
+		"if":  map[string]interface{}{"type": "integer"},
+		"then": map[string]interface{}{
+			"minimum": 10,
+		},
+		"else": map[string]interface{}{"maxLength": 3},
+	})
+
+	assert.NoError(t, err)
+
+	assert.Empty(t, sch.validate(10))
+	assert.Empty(t, sch.validate("abc"))
+	assert.Len(t, sch.validate(true), 1)
+	assert.Len(t, sch.validate(5), 1)
+	assert.Len(t, sch.validate("abcd"), 1)
+	assert.Len(t, sch.validate("forbidden"), 2)
+}
+
+func TestSchemaInstanceLocation(t *testing.T) {
+	t.Parallel()
+
+	sch, err := newSchema(map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"properties": map[string]interface{}{"a/b": map[string]interface{}{"type": "string"}}},
+	})
+
+	assert.NoError(t, err)
+
+	errs := sch.validate([]interface{}{map[string]interface{}{"a/b": "x"}, map[string]interface{}{"a/b": 1}})
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "/1/a~1b", errs[0].InstanceLocation)
+	assert.Equal(t, "/items/properties/a~1b/type", errs[0].KeywordLocation)
+}
+
+func TestFormatMatches(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, formatMatches("date-time", "2023-01-02T03:04:05Z"))
+	assert.False(t, formatMatches("date-time", "2023-01-02"))
+	assert.True(t, formatMatches("date", "2023-01-02"))
+	assert.True(t, formatMatches("time", "03:04:05Z"))
+	assert.True(t, formatMatches("uri", "https://example.com"))
+	assert.False(t, formatMatches("uri", "/relative"))
+	assert.True(t, formatMatches("ipv4", "127.0.0.1"))
+	assert.False(t, formatMatches("ipv4", "::1"))
+	assert.True(t, formatMatches("ipv6", "::1"))
+	assert.True(t, formatMatches("hostname", "example.com"))
+	assert.False(t, formatMatches("hostname", "-example.com"))
+	assert.False(t, formatMatches("regex", "("))
+	assert.True(t, formatMatches("unknown", "anything"))
+}
@@ -45,6 +45,34 @@ func (suite *scriptSuite) TestApplication() {
 	suite.Equal("Hello World!", body)
 }
 
+func (suite *scriptSuite) TestApplicationForm() {
+	host := suite.js(`
+	// js
+	const formApp = new Application({sync:true, bodyLimit:16})
+	formApp.post('/', (req, res) => {
+	  	res.json(req.body)
+  })
+	formApp.listen()
+	formApp.host
+	// !js
+	`)
+
+	resp, err := req.R().SetFormData(map[string]string{"name": "foo"}).Post("http://" + host.String())
+
+	suite.NoError(err)
+	suite.Equal(200, resp.GetStatusCode())
+
+	body, err := resp.ToString()
+
+	suite.NoError(err)
+	suite.JSONEq(`{"name":"foo"}`, body)
+
+	resp, err = req.R().SetFormData(map[string]string{"name": "longer than the body limit"}).Post("http://" + host.String())
+
+	suite.NoError(err)
+	suite.Equal(413, resp.GetStatusCode())
+}
+
 func (suite *scriptSuite) TestScriptMockUnmock() {
 	suite.js(`
 // js
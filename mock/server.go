@@ -0,0 +1,335 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// exchange holds the observed properties of a request served by a mock server.
+type exchange struct {
+	target   string
+	method   string
+	path     string
+	start    time.Time
+	route    *route
+	answered bool
+	status   int
+	duration time.Duration
+	auth     string
+}
+
+// server is the front-end of a mock definition. It serves requests by the handler of the muxpress application
+// (backend) in-process and observes them on the way.
+type server struct {
+	target   string
+	host     string
+	routes   *routeTable
+	backend  http.Handler
+	observe  func(*exchange)
+	diagnose bool
+	strict   bool
+	misses   *missLog
+	delay    time.Duration
+	mode     int
+	proto    string
+	roots    *x509.CertPool
+	logger   logrus.FieldLogger
+	listener net.Listener
+	local    string
+	http     *http.Server
+	stop     func()
+}
+
+const serverShutdownTimeout = 500 * time.Millisecond
+
+func newServer(target string, backend http.Handler, routes *routeTable, logger logrus.FieldLogger) *server {
+	srv := &server{ // nolint:exhaustruct
+		target:  target,
+		routes:  routes,
+		backend: backend,
+		logger:  logger.WithField("target", target),
+	}
+
+	if u, err := url.Parse(target); err == nil {
+		srv.host = u.Host
+	}
+
+	return srv
+}
+
+// listen starts the server on given network and address. The pipe network means in-memory listener,
+// the address is ignored in this case.
+func (srv *server) listen(network string, addr string) error {
+	var listener net.Listener
+
+	if network == pipeNetwork {
+		listener = newPipeListener()
+	} else {
+		var err error
+
+		if listener, err = net.Listen(network, addr); err != nil {
+			return err
+		}
+	}
+
+	srv.listener = listener
+
+	if u, err := url.Parse(srv.url()); err == nil {
+		srv.local = u.Host
+	}
+
+	srv.http = &http.Server{ // nolint:exhaustruct
+		Handler:           srv,
+		ReadHeaderTimeout: time.Minute,
+		ErrorLog:          log.New(srv.logger.WithField("source", "server").WriterLevel(logrus.DebugLevel), "", 0),
+	}
+
+	serve := srv.http.Serve
+
+	switch srv.proto {
+	case protoH2C:
+		srv.http.Handler = h2c.NewHandler(srv, &http2.Server{}) // nolint:exhaustruct
+	case protoH2, protoHTTPS:
+		cert, roots, err := newCertificate()
+		if err != nil {
+			listener.Close() // nolint:errcheck
+
+			return err
+		}
+
+		srv.roots = roots
+		srv.http.TLSConfig = &tls.Config{ // nolint:exhaustruct
+			Certificates: []tls.Certificate{*cert},
+			NextProtos:   []string{"http/1.1"},
+			MinVersion:   tls.VersionTLS12,
+		}
+
+		if srv.proto == protoH2 {
+			srv.http.TLSConfig.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+		}
+
+		serve = func(l net.Listener) error { return srv.http.ServeTLS(l, "", "") }
+	}
+
+	go func() {
+		if err := serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			srv.logger.WithError(err).Error("mock server aborted")
+		}
+	}()
+
+	return nil
+}
+
+// dial connects to the server, regardless of the given network and address.
+func (srv *server) dial(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if pipe, ok := srv.listener.(*pipeListener); ok {
+		return pipe.dial(ctx, network, addr)
+	}
+
+	var dialer net.Dialer
+
+	return dialer.DialContext(ctx, srv.listener.Addr().Network(), srv.listener.Addr().String())
+}
+
+// dialEntry returns the transport entry for reaching the server with its protocol.
+func (srv *server) dialEntry() *dialEntry {
+	return newDialEntry(srv.dial, srv.proto, srv.roots)
+}
+
+// url returns the URL of the server. Unspecified listen address (e.g. 0.0.0.0) is replaced by loopback address.
+func (srv *server) url() string {
+	addr := srv.listener.Addr().String()
+	scheme := "http://"
+
+	if secure(srv.proto) {
+		scheme = "https://"
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return scheme + addr
+	}
+
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = backendHost
+	}
+
+	return scheme + net.JoinHostPort(host, port)
+}
+
+// shutdown gracefully stops the mock server. Connections still active after the timeout are closed,
+// the error of the graceful shutdown is returned.
+func (srv *server) shutdown() error {
+	var err error
+
+	if srv.http != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+
+		if err = srv.http.Shutdown(ctx); err != nil {
+			srv.http.Close() // nolint:errcheck
+		}
+	}
+
+	if srv.stop != nil {
+		srv.stop()
+	}
+
+	return err
+}
+
+func (srv *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	if !srv.wait(r) {
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	// requests sent to the rewritten URL of the mock get the host of the target back
+	if len(srv.host) != 0 && r.Host == srv.local {
+		r.Host = srv.host
+	}
+
+	matched := srv.routes.match(r.Method, r.URL.Path)
+
+	// redirects and automatic OPTIONS responses of the router are not unmatched requests
+	answered := matched == nil && srv.routes.answers(r.Method, r.URL.Path)
+
+	// the authentication middlewares of the application record their failures in the result
+	r, result := withAuthResult(r)
+
+	if matched == nil && !answered {
+		// responses of the application get the CORS headers from the cors middleware,
+		// unmatched responses get them from the policy of the application
+		if policy := srv.routes.corsPolicy(); policy != nil {
+			policy.apply(rec.Header(), r.Header.Get("Origin"))
+		}
+
+		srv.unmatched(rec, r)
+	} else {
+		srv.backend.ServeHTTP(rec, r)
+	}
+
+	if srv.observe != nil {
+		srv.observe(&exchange{
+			target:   srv.target,
+			method:   r.Method,
+			path:     r.URL.Path,
+			start:    start,
+			route:    matched,
+			answered: answered,
+			status:   rec.status,
+			duration: time.Since(start),
+			auth:     result.scheme,
+		})
+	}
+}
+
+// wait delays the response by the delay of the mock definition. It returns false if the request
+// is canceled meanwhile.
+func (srv *server) wait(r *http.Request) bool {
+	if srv.delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(srv.delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// unmatched logs the near-miss routes of a request without matching route.
+// If diagnose or strict flag is set, the near-miss routes are also returned in the 404 response body,
+// otherwise the request is forwarded to the backend as usual. Unmatched requests of strict mocks
+// are collected for failing the request call.
+func (srv *server) unmatched(w http.ResponseWriter, r *http.Request) {
+	misses := srv.routes.nearest(r.Method, r.URL.Path)
+	candidates := make([]string, 0, len(misses))
+
+	for _, miss := range misses {
+		candidates = append(candidates, miss.String())
+	}
+
+	logger := srv.logger.WithFields(logrus.Fields{"method": r.Method, "path": r.URL.Path})
+	if len(candidates) != 0 {
+		logger = logger.WithField("candidates", candidates)
+	}
+
+	if srv.strict {
+		logger.Error("no route matches mock request")
+		srv.misses.add(srv.target, r.Method+" "+srv.target+r.URL.Path)
+	} else {
+		logger.Warn("no route matches mock request")
+	}
+
+	if !srv.diagnose && !srv.strict {
+		srv.backend.ServeHTTP(w, r)
+
+		return
+	}
+
+	body := map[string]interface{}{
+		"error":      "no route matches mock request",
+		"target":     srv.target,
+		"method":     r.Method,
+		"path":       r.URL.Path,
+		"candidates": candidates,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		srv.logger.WithError(err).Debug("diagnostic response failed")
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	if !rec.wroteHeader {
+		rec.status = code
+		rec.wroteHeader = true
+	}
+
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *statusRecorder) Flush() {
+	if flusher, ok := rec.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
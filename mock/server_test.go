@@ -0,0 +1,285 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("Hello " + r.URL.Path)) // nolint:errcheck
+	})
+
+	routes := newRouteTable()
+	routes.add(http.MethodPost, "/users/:name")
+
+	srv := newServer("https://example.com", backend, routes, logrus.StandardLogger())
+
+	var observed []*exchange
+
+	srv.observe = func(exc *exchange) {
+		observed = append(observed, exc)
+	}
+
+	assert.NoError(t, srv.listen("tcp", "127.0.0.1:0"))
+	assert.True(t, strings.HasPrefix(srv.url(), "http://127.0.0.1:"))
+
+	resp, err := req.Post(srv.url() + "/users/alice")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.GetStatusCode())
+	assert.Equal(t, "Hello /users/alice", resp.String())
+
+	resp, err = req.Get(srv.url() + "/missing")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.GetStatusCode())
+
+	assert.Len(t, observed, 2)
+
+	assert.Equal(t, "https://example.com", observed[0].target)
+	assert.Equal(t, http.MethodPost, observed[0].method)
+	assert.Equal(t, "/users/:name", observed[0].route.path)
+	assert.Equal(t, http.StatusCreated, observed[0].status)
+	assert.Positive(t, observed[0].duration)
+
+	assert.Nil(t, observed[1].route)
+	assert.Equal(t, "/missing", observed[1].path)
+	assert.Equal(t, http.StatusNotFound, observed[1].status)
+
+	assert.NoError(t, srv.shutdown())
+
+	_, err = req.Get(srv.url())
+
+	assert.Error(t, err)
+}
+
+func TestServerListenError(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer("https://example.com", http.NotFoundHandler(), newRouteTable(), logrus.StandardLogger())
+
+	assert.Error(t, srv.listen("tcp", "invalid address"))
+	assert.NoError(t, srv.shutdown())
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+
+	defer close(release)
+
+	started := make(chan struct{})
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	srv := newServer("https://example.com", handler, newRouteTable(), logrus.StandardLogger())
+
+	assert.NoError(t, srv.listen("tcp", "127.0.0.1:0"))
+
+	go req.Get(srv.url()) // nolint:errcheck
+
+	<-started
+
+	// the active request is not finished in time, the connection is closed
+	assert.ErrorIs(t, srv.shutdown(), context.DeadlineExceeded)
+}
+
+func TestStatusRecorder(t *testing.T) {
+	t.Parallel()
+
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder(), status: http.StatusOK}
+
+	rec.Write([]byte("foo")) // nolint:errcheck
+	rec.WriteHeader(http.StatusTeapot)
+	rec.Flush()
+
+	assert.Equal(t, http.StatusOK, rec.status)
+}
+
+func TestServerDiagnose(t *testing.T) {
+	t.Parallel()
+
+	backend := http.NotFoundHandler()
+
+	routes := newRouteTable()
+	routes.add(http.MethodPost, "/users")
+
+	srv := newServer("https://example.com", backend, routes, logrus.StandardLogger())
+
+	assert.NoError(t, srv.listen("tcp", "127.0.0.1:0"))
+
+	defer srv.shutdown()
+
+	resp, err := req.Get(srv.url() + "/users")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.GetStatusCode())
+	assert.NotContains(t, resp.String(), "candidates")
+
+	srv.diagnose = true
+
+	resp, err = req.Get(srv.url() + "/users")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.GetStatusCode())
+	assert.Equal(t, "application/json", resp.GetHeader("Content-Type"))
+
+	var body map[string]interface{}
+
+	assert.NoError(t, resp.UnmarshalJson(&body))
+	assert.Equal(t, "https://example.com", body["target"])
+	assert.Equal(t, http.MethodGet, body["method"])
+	assert.Equal(t, "/users", body["path"])
+	assert.Equal(t, []interface{}{"POST /users (method mismatch, route accepts POST)"}, body["candidates"])
+}
+
+func TestServerStrict(t *testing.T) {
+	t.Parallel()
+
+	routes := newRouteTable()
+	routes.add(http.MethodPost, "/users")
+
+	srv := newServer("https://example.com", http.NotFoundHandler(), routes, logrus.StandardLogger())
+	srv.strict, srv.misses = true, new(missLog)
+
+	assert.NoError(t, srv.listen("tcp", "127.0.0.1:0"))
+
+	defer srv.shutdown()
+
+	resp, err := req.Get(srv.url() + "/users")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.GetStatusCode())
+	assert.Contains(t, resp.String(), "candidates")
+	assert.Equal(t, []string{"GET https://example.com/users"}, srv.misses.take("https://example.com"))
+}
+
+func TestServerDelay(t *testing.T) {
+	t.Parallel()
+
+	routes := newRouteTable()
+	routes.add(http.MethodGet, "/")
+
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	srv := newServer("https://example.com", backend, routes, logrus.StandardLogger())
+	srv.delay = 50 * time.Millisecond
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.GreaterOrEqual(t, time.Since(start), srv.delay)
+
+	// canceled requests are not served
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(t, srv.wait(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)))
+}
+
+func TestServerURL(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer("https://example.com", http.NotFoundHandler(), newRouteTable(), logrus.StandardLogger())
+
+	assert.NoError(t, srv.listen("tcp", "0.0.0.0:0"))
+
+	defer srv.shutdown()
+
+	assert.True(t, strings.HasPrefix(srv.url(), "http://127.0.0.1:"))
+}
+
+func TestServerMemory(t *testing.T) {
+	t.Parallel()
+
+	srv := newServer("https://example.com", http.NotFoundHandler(), newRouteTable(), logrus.StandardLogger())
+
+	assert.NoError(t, srv.listen(pipeNetwork, ""))
+
+	conn, err := srv.dial(context.Background(), "tcp", "ignored:80")
+
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Close())
+
+	assert.NoError(t, srv.shutdown())
+
+	_, err = srv.dial(context.Background(), "tcp", "ignored:80")
+
+	assert.ErrorIs(t, err, net.ErrClosed)
+}
+
+func TestServerAnswered(t *testing.T) {
+	t.Parallel()
+
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "true")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	routes := newRouteTable()
+	routes.add(http.MethodGet, "/users")
+
+	srv := newServer("https://example.com", backend, routes, logrus.StandardLogger())
+	srv.diagnose = true
+
+	var observed []*exchange
+
+	srv.observe = func(exc *exchange) {
+		observed = append(observed, exc)
+	}
+
+	assert.NoError(t, srv.listen("tcp", "127.0.0.1:0"))
+
+	defer srv.shutdown()
+
+	client := req.C().SetRedirectPolicy(req.NoRedirectPolicy())
+
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		path := "/users"
+		if method == http.MethodGet {
+			path += "/"
+		}
+
+		resp, err := client.R().Send(method, srv.url()+path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "true", resp.GetHeader("X-Backend"), method)
+	}
+
+	assert.Len(t, observed, 2)
+
+	for _, exc := range observed {
+		assert.Nil(t, exc.route)
+		assert.True(t, exc.answered)
+	}
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"regexp"
+	"strings"
+
+	"go.k6.io/k6/js/modules"
+)
+
+// switchEnv is the name of the environment variable selecting the active mock definitions.
+const switchEnv = "K6_MOCK"
+
+// Special values of K6_MOCK environment variable.
+const (
+	switchAll  = "all"
+	switchNone = "none"
+)
+
+// mockSwitch selects the active mock definitions by name or target.
+// The nil patterns means all mock definitions are active.
+type mockSwitch struct {
+	patterns []*regexp.Regexp
+}
+
+// newMockSwitch parses the value of K6_MOCK environment variable: all, none,
+// or comma separated list of names or targets, which may contain * and ? wildcards.
+func newMockSwitch(str string) *mockSwitch {
+	str = strings.TrimSpace(str)
+
+	switch strings.ToLower(str) {
+	case "", switchAll:
+		return &mockSwitch{patterns: nil}
+	case switchNone:
+		return &mockSwitch{patterns: []*regexp.Regexp{}}
+	}
+
+	patterns := make([]*regexp.Regexp, 0)
+
+	for _, glob := range strings.Split(str, ",") {
+		if glob = strings.TrimSpace(glob); len(glob) != 0 {
+			patterns = append(patterns, globRegexp(glob))
+		}
+	}
+
+	return &mockSwitch{patterns: patterns}
+}
+
+// globRegexp returns regular expression for glob pattern, where * matches any sequence
+// of characters (including /) and ? matches any single character.
+func globRegexp(glob string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(glob)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+
+	return regexp.MustCompile("^" + expr + "$")
+}
+
+// active returns true if the mock definition with given name or target is selected.
+func (sw *mockSwitch) active(name string, target string) bool {
+	if sw.patterns == nil {
+		return true
+	}
+
+	for _, re := range sw.patterns {
+		if re.MatchString(name) || re.MatchString(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envSwitch returns the mock switch from K6_MOCK environment variable.
+// The environment is available only in init context, where module instances are created.
+func envSwitch(vu modules.VU) *mockSwitch { // nolint:varnamelen
+	var str string
+
+	if env := vu.InitEnv(); env != nil && env.LookupEnv != nil {
+		str, _ = env.LookupEnv(switchEnv)
+	}
+
+	return newMockSwitch(str)
+}
+
+// active returns true if the mock definition is selected by K6_MOCK environment variable.
+func (mod *Module) active(name string, target string) bool {
+	return mod.selector.active(name, target)
+}
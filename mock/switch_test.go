@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockSwitch(t *testing.T) {
+	t.Parallel()
+
+	for _, str := range []string{"", "all", " ALL "} {
+		assert.True(t, newMockSwitch(str).active("api", "https://example.com"), str)
+	}
+
+	assert.False(t, newMockSwitch("none").active("api", "https://example.com"))
+
+	sw := newMockSwitch("api, https://*.example.net,, db?")
+
+	assert.True(t, sw.active("api", "https://example.com"))
+	assert.True(t, sw.active("other", "https://www.example.net"))
+	assert.True(t, sw.active("db1", "https://example.com"))
+	assert.False(t, sw.active("db12", "https://example.com"))
+	assert.False(t, sw.active("apis", "https://example.com"))
+	assert.False(t, sw.active("https://example.net", "https://example.net"))
+}
+
+func TestModuleSwitch(t *testing.T) {
+	t.Parallel()
+
+	real := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real")) // nolint:errcheck
+	}))
+
+	defer real.Close()
+
+	helper := newHelperEnv(t, map[string]string{switchEnv: "api"})
+
+	assert.NoError(t, helper.vu.Runtime().Set("real", real.URL))
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://example.com", app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { sync: true, name: "api" })
+
+mock(real, app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { sync: true, name: "local" })
+
+mock.disable("local")
+mock.enable("local")
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com"}, helper.module.targets())
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`[http.get(real + "/").body, http.get(real + "/", { mock: "local" }).body]`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"real", "real"}, value.Export())
+}
+
+func TestModuleSwitchSetup(t *testing.T) {
+	t.Parallel()
+
+	real := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real")) // nolint:errcheck
+	}))
+
+	defer real.Close()
+
+	helper := newHelperEnv(t, map[string]string{switchEnv: "none"})
+
+	assert.NoError(t, helper.vu.Runtime().Set("real", real.URL))
+	assert.NoError(t, helper.vu.Runtime().Set("__VU", 0))
+
+	// the environment is not available in setup
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+mock(real, app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { sync: true })
+
+http.get(real + "/").body
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "real", value.Export())
+	assert.Empty(t, helper.module.targets())
+}
@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// dialHost is the host name used in the request URL sent to dialed mock servers.
+// Dialing ignores the address, so it can be anything.
+const dialHost = "mock"
+
+// mockHeader is the request header selecting the mock (by target) for the transport.
+// The bypassing value means that the request should not be mocked. The header is removed before sending.
+const (
+	mockHeader       = "X-Xk6-Mock"
+	mockHeaderBypass = "false"
+)
+
+type dialFunc func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+// dialTable holds the transports of mock servers which cannot be reached by rewriting the request URL
+// (e.g. listening on Unix domain socket). Requests of these mocks keep their original URL.
+type dialTable struct {
+	mu      sync.RWMutex
+	entries map[string]*dialEntry
+}
+
+// dialEntry is the transport of a mock server and the URL scheme used by the transport.
+type dialEntry struct {
+	transport http.RoundTripper
+	scheme    string
+	close     func()
+	disabled  bool
+}
+
+func newDialTable() *dialTable {
+	return &dialTable{entries: make(map[string]*dialEntry)} // nolint:exhaustruct
+}
+
+// add registers the mock server of target, which talks HTTP/1.1 over the connections created by dial.
+func (table *dialTable) add(target string, dial dialFunc) {
+	table.set(target, newDialEntry(dial, protoHTTP1, nil))
+}
+
+func (table *dialTable) set(target string, entry *dialEntry) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	table.entries[target] = entry
+}
+
+func (table *dialTable) remove(target string) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	if entry, found := table.entries[target]; found {
+		delete(table.entries, target)
+		entry.close()
+	}
+}
+
+// disable sets the disabled flag of the mock of target. Disabled mocks are ignored by match.
+func (table *dialTable) disable(target string, disabled bool) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+
+	if entry, found := table.entries[target]; found {
+		entry.disabled = disabled
+	}
+}
+
+// match returns the target (longest matching prefix) and the transport entry of the mock for given URL.
+func (table *dialTable) match(loc string) (string, *dialEntry) {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+
+	var (
+		target string
+		entry  *dialEntry
+	)
+
+	for k, v := range table.entries {
+		if strings.HasPrefix(loc, k) && len(k) > len(target) && !v.disabled {
+			target, entry = k, v
+		}
+	}
+
+	return target, entry
+}
+
+func (table *dialTable) get(target string) *dialEntry {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+
+	return table.entries[target]
+}
+
+func (table *dialTable) empty() bool {
+	table.mu.RLock()
+	defer table.mu.RUnlock()
+
+	return len(table.entries) == 0
+}
+
+// mockTransport sends requests of dialed mocks to the mock server, other requests are sent by the base transport.
+type mockTransport struct {
+	base  http.RoundTripper
+	mocks *dialTable
+}
+
+func (t *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	loc := req.URL.String()
+	selected := req.Header.Get(mockHeader)
+	out := req
+
+	if len(selected) != 0 {
+		out = req.Clone(req.Context())
+		out.Header.Del(mockHeader)
+	}
+
+	var (
+		target string
+		entry  *dialEntry
+	)
+
+	switch selected {
+	case "":
+		target, entry = t.mocks.match(loc)
+	case mockHeaderBypass:
+	default:
+		target, entry = selected, t.mocks.get(selected)
+	}
+
+	if entry == nil {
+		return t.base.RoundTrip(out)
+	}
+
+	u, err := url.Parse(strings.Replace(loc, target, entry.scheme+"://"+dialHost, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	if out == req {
+		out = req.Clone(req.Context())
+	}
+
+	out.URL = u
+	out.Host = req.URL.Host
+
+	resp, err := entry.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Request = req
+
+	return resp, nil
+}
+
+// installTransport wraps the transport of the VU, so requests of dialed mocks are sent to the mock servers.
+func (mod *Module) installTransport() {
+	state := mod.vu.State()
+	if state == nil || state.Transport == nil {
+		return
+	}
+
+	if _, installed := state.Transport.(*mockTransport); installed {
+		return
+	}
+
+	state.Transport = &mockTransport{base: state.Transport, mocks: mod.dialers}
+}
@@ -0,0 +1,326 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/sobek"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialTable(t *testing.T) {
+	t.Parallel()
+
+	table := newDialTable()
+
+	assert.True(t, table.empty())
+
+	var dialer net.Dialer
+
+	table.add("https://example.com", dialer.DialContext)
+	table.add("https://example.com/api", dialer.DialContext)
+
+	assert.False(t, table.empty())
+
+	target, entry := table.match("https://example.com/api/users")
+
+	assert.Equal(t, "https://example.com/api", target)
+	assert.NotNil(t, entry)
+	assert.Equal(t, "http", entry.scheme)
+
+	target, _ = table.match("https://example.com/users")
+
+	assert.Equal(t, "https://example.com", target)
+
+	target, entry = table.match("https://example.net/")
+
+	assert.Empty(t, target)
+	assert.Nil(t, entry)
+
+	table.remove("https://example.com")
+	table.remove("https://example.com/api")
+	table.remove("https://example.com/api")
+
+	assert.True(t, table.empty())
+}
+
+func TestMockTransport(t *testing.T) {
+	t.Parallel()
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.Path)) // nolint:errcheck
+	}))
+
+	defer backend.Close()
+
+	table := newDialTable()
+
+	table.add("https://example.com/api", func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+
+		return dialer.DialContext(ctx, "tcp", backend.Listener.Addr().String())
+	})
+
+	base := &recordingTransport{}
+	transport := &mockTransport{base: base, mocks: table}
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/api/users", nil)
+	req.RequestURI = ""
+
+	resp, err := transport.RoundTrip(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, req, resp.Request)
+	assert.Equal(t, "example.com /users", readBody(t, resp))
+	assert.Nil(t, base.req)
+
+	req = httptest.NewRequest(http.MethodGet, "https://example.net/", nil)
+
+	_, err = transport.RoundTrip(req)
+
+	assert.ErrorIs(t, err, errRecorded)
+	assert.Equal(t, req, base.req)
+
+	req = httptest.NewRequest(http.MethodGet, "https://example.com/api/users", nil)
+	req.Header.Set(mockHeader, mockHeaderBypass)
+
+	_, err = transport.RoundTrip(req)
+
+	assert.ErrorIs(t, err, errRecorded)
+	assert.Equal(t, "https://example.com/api/users", base.req.URL.String())
+	assert.Empty(t, base.req.Header.Get(mockHeader))
+
+	base.req = nil
+
+	req = httptest.NewRequest(http.MethodGet, "https://example.com/api/users", nil)
+	req.RequestURI = ""
+	req.Header.Set(mockHeader, "https://example.com/api")
+
+	resp, err = transport.RoundTrip(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com /users", readBody(t, resp))
+	assert.Nil(t, base.req)
+}
+
+func TestModuleMockParamDialed(t *testing.T) {
+	t.Parallel()
+
+	real := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("real " + r.Header.Get(mockHeader))) // nolint:errcheck
+	}))
+
+	defer real.Close()
+
+	helper := newHelper(t)
+
+	assert.NoError(t, helper.vu.Runtime().Set("real", real.URL))
+
+	_, err := helper.vu.Runtime().RunString(`
+mock(real, app => {
+  app.get("/", (req, res) => res.text("mock"))
+}, { sync: true, memory: true })
+`)
+
+	assert.NoError(t, err)
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+;[http.get(real + "/").body, http.get(real + "/", { mock: false }).body, http.get(real + "/", { mock: real }).body]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"mock", "real ", "mock"}, value.Export())
+}
+
+func TestModuleSocket(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://socket.example.com", app => {
+  app.get("/hello", (req, res) => res.json({ greeting: "Hello" }))
+}, { sync: true, socket: true })
+`)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, helper.module.lookup, "https://socket.example.com")
+
+	srv := helper.module.servers["https://socket.example.com"]
+
+	assert.Equal(t, "unix", srv.listener.Addr().Network())
+	assert.FileExists(t, srv.listener.Addr().String())
+
+	// the application is served on the socket, it does not listen on TCP port
+	assert.True(t, sobek.IsNull(helper.module.apps["https://socket.example.com"].Get("host")))
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+const res = http.get("https://socket.example.com/hello")
+;[res.status, res.url, res.json().greeting]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(200), "https://socket.example.com/hello", "Hello"}, value.Export())
+	assert.IsType(t, &mockTransport{}, helper.vu.State().Transport) // nolint:exhaustruct
+
+	_, err = helper.vu.Runtime().RunString(`unmock("https://socket.example.com")`)
+
+	assert.NoError(t, err)
+	assert.True(t, helper.module.dialers.empty())
+	assert.NoFileExists(t, srv.listener.Addr().String())
+}
+
+func TestModuleMemory(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+
+	_, err := helper.vu.Runtime().RunString(`
+mock("https://memory.example.com/api", app => {
+  app.post("/items", (req, res) => {
+    res.status(201)
+    res.json({ name: req.body.name })
+  })
+}, { sync: true, memory: true })
+`)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, helper.module.lookup, "https://memory.example.com/api")
+	assert.Equal(t, pipeNetwork, helper.module.servers["https://memory.example.com/api"].listener.Addr().Network())
+	assert.True(t, sobek.IsNull(helper.module.apps["https://memory.example.com/api"].Get("host")))
+
+	moveToHTTPContext(helper)
+
+	value, err := helper.vu.Runtime().RunString(`
+const res = http.post("https://memory.example.com/api/items", JSON.stringify({ name: "foo" }),
+  { headers: { "Content-Type": "application/json" } })
+;[res.status, res.url, res.json().name]
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(201), "https://memory.example.com/api/items", "foo"}, value.Export())
+
+	_, err = helper.vu.Runtime().RunString(`
+mock("https://other.example.com", app => {}, { sync: true, memory: true, socket: true })
+`)
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Parallel()
+
+	helper := newHelper(t)
+	target := "https://example.com"
+
+	path := func(opts *options) string {
+		socket, err := helper.module.socketPath(target, opts, 1)
+
+		assert.NoError(t, err)
+
+		return socket
+	}
+
+	first := path(&options{unix: true})  // nolint:exhaustruct
+	second := path(&options{unix: true}) // nolint:exhaustruct
+
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, os.TempDir(), filepath.Dir(first))
+
+	assert.Equal(t, "/tmp/mock.sock", path(&options{unix: true, socket: "/tmp/mock.sock"})) // nolint:exhaustruct
+
+	_, err := helper.module.socketPath(target, &options{unix: true, port: 8080}, 1) // nolint:exhaustruct
+
+	assert.ErrorIs(t, err, errInvalidArg)
+}
+
+var errRecorded = errors.New("recorded")
+
+type recordingTransport struct {
+	req *http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+
+	return nil, errRecorded
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	defer resp.Body.Close() // nolint:errcheck
+
+	data, err := io.ReadAll(resp.Body)
+
+	assert.NoError(t, err)
+
+	return string(data)
+}
+
+// BenchmarkTransport compares the listener based (TCP, Unix domain socket) and the in-memory
+// way of reaching the mock server. The backend is called in-process in all cases, so only the
+// connection between the client and the mock server differs.
+func BenchmarkTransport(b *testing.B) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello")) // nolint:errcheck
+	})
+
+	bench := func(b *testing.B, network string, addr string) {
+		b.Helper()
+
+		routes := newRouteTable()
+		routes.add(http.MethodGet, "/")
+
+		srv := newServer("https://example.com", backend, routes, logrus.StandardLogger())
+
+		if err := srv.listen(network, addr); err != nil {
+			b.Fatal(err)
+		}
+
+		defer srv.shutdown()
+
+		base := http.DefaultTransport.(*http.Transport).Clone() // nolint:forcetypeassert
+		table := newDialTable()
+		client := &http.Client{Transport: &mockTransport{base: base, mocks: table}} // nolint:exhaustruct
+		loc := "https://example.com/"
+
+		if network == "tcp" {
+			loc = srv.url() + "/"
+		} else {
+			table.add("https://example.com", srv.dial)
+		}
+
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			resp, err := client.Get(loc) // nolint:noctx
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			io.Copy(io.Discard, resp.Body) // nolint:errcheck
+			resp.Body.Close()              // nolint:errcheck
+		}
+	}
+
+	b.Run("tcp", func(b *testing.B) { bench(b, "tcp", "127.0.0.1:0") })
+	b.Run("unix", func(b *testing.B) { bench(b, "unix", filepath.Join(b.TempDir(), "mock.sock")) })
+	b.Run("memory", func(b *testing.B) { bench(b, pipeNetwork, "") })
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxUnmatchedSummary is the maximum number of distinct requests listed in the end-of-test summary.
+const maxUnmatchedSummary = 10
+
+// unmatchedLog collects requests without matching route from all VUs.
+type unmatchedLog struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+type unmatchedEntry struct {
+	request string
+	count   int
+}
+
+func newUnmatchedLog() *unmatchedLog {
+	return &unmatchedLog{counts: make(map[string]int)} // nolint:exhaustruct
+}
+
+func (ulog *unmatchedLog) add(exc *exchange) {
+	ulog.mu.Lock()
+	defer ulog.mu.Unlock()
+
+	ulog.counts[exc.method+" "+exc.target+exc.path]++
+}
+
+// entries returns the collected requests, the most frequent first.
+func (ulog *unmatchedLog) entries() []unmatchedEntry {
+	ulog.mu.Lock()
+	defer ulog.mu.Unlock()
+
+	entries := make([]unmatchedEntry, 0, len(ulog.counts))
+
+	for request, count := range ulog.counts {
+		entries = append(entries, unmatchedEntry{request: request, count: count})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+
+		return entries[i].request < entries[j].request
+	})
+
+	return entries
+}
+
+// report logs the summary of unmatched requests.
+func (ulog *unmatchedLog) report(logger logrus.FieldLogger) {
+	entries := ulog.entries()
+	if len(entries) == 0 {
+		return
+	}
+
+	total := 0
+
+	for _, entry := range entries {
+		total += entry.count
+	}
+
+	logger.WithFields(logrus.Fields{"requests": total, "distinct": len(entries)}).Warn("unmatched mock requests")
+
+	for idx, entry := range entries {
+		if idx == maxUnmatchedSummary {
+			logger.Warnf("  ... and %d more", len(entries)-maxUnmatchedSummary)
+
+			break
+		}
+
+		logger.Warnf("  %d x %s", entry.count, entry.request)
+	}
+}
+
+// missLog collects unmatched requests of strict mock definitions by target, until they are taken
+// by the synchronous request call that sent them.
+type missLog struct {
+	mu     sync.Mutex
+	misses map[string][]string
+}
+
+func (mlog *missLog) add(target string, request string) {
+	mlog.mu.Lock()
+	defer mlog.mu.Unlock()
+
+	if mlog.misses == nil {
+		mlog.misses = make(map[string][]string)
+	}
+
+	mlog.misses[target] = append(mlog.misses[target], request)
+}
+
+// take returns and forgets the unmatched requests of given target.
+func (mlog *missLog) take(target string) []string {
+	mlog.mu.Lock()
+	defer mlog.mu.Unlock()
+
+	misses := mlog.misses[target]
+
+	delete(mlog.misses, target)
+
+	return misses
+}
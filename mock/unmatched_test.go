@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmatchedLog(t *testing.T) {
+	t.Parallel()
+
+	ulog := newUnmatchedLog()
+
+	ulog.add(&exchange{target: "https://example.com", method: "GET", path: "/foo"})
+	ulog.add(&exchange{target: "https://example.com", method: "GET", path: "/bar"})
+	ulog.add(&exchange{target: "https://example.com", method: "GET", path: "/bar"})
+
+	assert.Equal(t, []unmatchedEntry{
+		{request: "GET https://example.com/bar", count: 2},
+		{request: "GET https://example.com/foo", count: 1},
+	}, ulog.entries())
+
+	logger, hook := test.NewNullLogger()
+
+	ulog.report(logger)
+
+	assert.Len(t, hook.AllEntries(), 3)
+	assert.Equal(t, "unmatched mock requests", hook.AllEntries()[0].Message)
+	assert.Equal(t, 3, hook.AllEntries()[0].Data["requests"])
+	assert.Equal(t, "  2 x GET https://example.com/bar", hook.AllEntries()[1].Message)
+
+	hook.Reset()
+
+	newUnmatchedLog().report(logger)
+
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestUnmatchedLogTruncate(t *testing.T) {
+	t.Parallel()
+
+	ulog := newUnmatchedLog()
+
+	for i := 0; i < maxUnmatchedSummary+2; i++ {
+		ulog.add(&exchange{target: "https://example.com", method: "GET", path: "/" + string(rune('a'+i))})
+	}
+
+	logger, hook := test.NewNullLogger()
+
+	ulog.report(logger)
+
+	assert.Len(t, hook.AllEntries(), maxUnmatchedSummary+2)
+	assert.Equal(t, "  ... and 2 more", hook.LastEntry().Message)
+}
+
+func TestMissLog(t *testing.T) {
+	t.Parallel()
+
+	mlog := new(missLog)
+
+	assert.Empty(t, mlog.take("https://example.com"))
+
+	mlog.add("https://example.com", "GET https://example.com/foo")
+	mlog.add("https://example.com", "GET https://example.com/bar")
+	mlog.add("https://example.net", "GET https://example.net/foo")
+
+	assert.Equal(t, []string{"GET https://example.com/foo", "GET https://example.com/bar"}, mlog.take("https://example.com"))
+	assert.Empty(t, mlog.take("https://example.com"))
+	assert.Equal(t, []string{"GET https://example.net/foo"}, mlog.take("https://example.net"))
+}
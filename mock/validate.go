@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/grafana/sobek"
+	"go.k6.io/k6/lib/fsext"
+)
+
+func (mod *Module) loadSchema(value sobek.Value) *schema {
+	if value == nil || sobek.IsUndefined(value) || sobek.IsNull(value) {
+		mod.throwf("missing schema", errInvalidArg)
+	}
+
+	var (
+		sch *schema
+		err error
+	)
+
+	if value.ExportType().Kind() == reflect.String {
+		sch, err = parseSchema(mod.readFile(value.String()))
+	} else {
+		sch, err = newSchema(value.Export())
+	}
+
+	if err != nil {
+		mod.throw(err)
+	}
+
+	return sch
+}
+
+func (mod *Module) readFile(filename string) []byte {
+	env := mod.vu.InitEnv()
+	if env == nil {
+		mod.throwf("file %s can only be loaded in the init context", errInvalidArg, filename)
+	}
+
+	data, err := fsext.ReadFile(env.FileSystems["file"], env.GetAbsFilePath(filename))
+	if err != nil {
+		mod.throw(err)
+	}
+
+	return data
+}
+
+// validate returns a middleware which validates request body against the given JSON Schema.
+func (mod *Module) validate(call sobek.FunctionCall) sobek.Value {
+	runtime := mod.runtime()
+	sch := mod.loadSchema(call.Argument(0))
+	status := http.StatusBadRequest
+	target := mod.defining
+
+	if obj, ok := call.Argument(1).(*sobek.Object); ok {
+		if v := obj.Get("status"); v != nil && !sobek.IsUndefined(v) {
+			status = int(v.ToInteger())
+		}
+	}
+
+	middleware := func(mw sobek.FunctionCall) sobek.Value {
+		req := mw.Argument(0).ToObject(runtime)
+		res := mw.Argument(1).ToObject(runtime)
+
+		var instance interface{}
+
+		if body := req.Get("body"); body != nil && !sobek.IsUndefined(body) {
+			instance = body.Export()
+		}
+
+		errs := sch.validate(instance)
+		if len(errs) == 0 {
+			if next, ok := sobek.AssertFunction(mw.Argument(2)); ok {
+				if _, err := next(sobek.Undefined()); err != nil {
+					mod.throw(err)
+				}
+			}
+
+			return sobek.Undefined()
+		}
+
+		method := req.Get("method").String()
+
+		mod.logger.WithField("target", target).WithField("method", method).WithField("path", req.Get("path").String()).
+			WithField("errors", len(errs)).Debug("request body validation failed")
+
+		mod.push(mod.metrics.schemaViolations, 1, map[string]string{"target": target, "method": method})
+
+		mod.invoke(res, "set", "Content-Type", "application/json; charset=utf-8")
+		mod.invoke(res, "status", status)
+		mod.invoke(res, "json", map[string]interface{}{"valid": false, "errors": errs})
+
+		return sobek.Undefined()
+	}
+
+	return runtime.ToValue(middleware)
+}
+
+// invoke calls the named method of the given object.
+func (mod *Module) invoke(this *sobek.Object, method string, args ...interface{}) sobek.Value {
+	callable, ok := sobek.AssertFunction(this.Get(method))
+	if !ok {
+		mod.throwf("%s must be callable", errInvalidArg, method)
+	}
+
+	values := make([]sobek.Value, len(args))
+
+	for idx, arg := range args {
+		values[idx] = mod.runtime().ToValue(arg)
+	}
+
+	v, err := callable(this, values...)
+	if err != nil {
+		mod.throw(err)
+	}
+
+	return v
+}
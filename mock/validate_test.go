@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2023 Iván Szkiba
+//
+// SPDX-License-Identifier: MIT
+
+package mock
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/suite"
+	"go.k6.io/k6/lib/fsext"
+)
+
+type validateSuite struct {
+	suiteBase
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	suite.Run(t, new(validateSuite))
+}
+
+func (suite *validateSuite) SetupSuite() {
+	suite.suiteBase.SetupSuite()
+
+	filesystem := fsext.NewMemMapFs()
+
+	suite.NoError(afero.WriteFile(filesystem, "/schemas/user.json", []byte(`{"type":"object","required":["name"]}`), 0o644))
+
+	suite.vu.InitEnvField.FileSystems = map[string]fsext.Fs{"file": filesystem}
+	suite.vu.InitEnvField.CWD = &url.URL{Scheme: "file", Path: "/schemas/"} // nolint:exhaustruct
+}
+
+func (suite *validateSuite) TestInline() {
+	suite.js(`
+// js
+const schema = {
+  type: "object",
+  required: ["name"],
+  properties: { name: { type: "string" } }
+}
+
+mock("https://inline.example.com", app => {
+  app.post("/users", mock.validate(schema, { status: 422 }), (req, res) => {
+    res.json({ name: req.body.name })
+  })
+}, { sync: true })
+// !js
+`)
+
+	base := suite.module.lookup["https://inline.example.com"]
+
+	resp, err := req.R().SetBodyJsonString(`{"name":"Alice"}`).Post(base + "/users")
+
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.GetStatusCode())
+
+	var result struct {
+		Valid  bool          `json:"valid"`
+		Errors []schemaError `json:"errors"`
+	}
+
+	resp, err = req.R().SetBodyJsonString(`{"name":1}`).SetSuccessResult(&result).Post(base + "/users")
+
+	suite.NoError(err)
+	suite.Equal(http.StatusUnprocessableEntity, resp.GetStatusCode())
+	suite.Contains(resp.GetHeader("Content-Type"), "application/json")
+
+	suite.NoError(resp.Into(&result))
+	suite.False(result.Valid)
+	suite.Len(result.Errors, 1)
+	suite.Equal("/properties/name/type", result.Errors[0].KeywordLocation)
+	suite.Equal("/name", result.Errors[0].InstanceLocation)
+}
+
+func (suite *validateSuite) TestFile() {
+	suite.js(`
+// js
+mock("https://file.example.com", app => {
+  app.post("/users", mock.validate("user.json"), (req, res) => {
+    res.json({ name: req.body.name })
+  })
+}, { sync: true })
+// !js
+`)
+
+	base := suite.module.lookup["https://file.example.com"]
+
+	resp, err := req.R().SetBodyJsonString(`{"name":"Bob"}`).Post(base + "/users")
+
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, resp.GetStatusCode())
+
+	resp, err = req.R().Post(base + "/users")
+
+	suite.NoError(err)
+	suite.Equal(http.StatusBadRequest, resp.GetStatusCode())
+}
+
+func (suite *validateSuite) TestInvalid() {
+	_, err := suite.run(`mock.validate()`)
+
+	suite.Error(err)
+
+	_, err = suite.run(`mock.validate("missing.json")`)
+
+	suite.Error(err)
+
+	_, err = suite.run(`mock.validate({ pattern: "(" })`)
+
+	suite.Error(err)
+}
@@ -0,0 +1,19 @@
+import http from 'k6/x/mock'
+import { check } from 'k6'
+import { test } from 'k6/execution'
+import { defineMocks } from './serve/mocks.js'
+
+defineMocks()
+
+export default function () {
+  const res = http.get('https://api.example.com/greeting')
+  const ok = check(res, {
+    'response code was 200': res => res.status == 200,
+    '"greeting" was "Hello World!"': res =>
+      res.json('greeting') == 'Hello World!'
+  })
+
+  if (!ok) {
+    test.abort('unexpected response')
+  }
+}
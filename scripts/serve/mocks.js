@@ -0,0 +1,19 @@
+import { mock } from 'k6/x/mock'
+
+// defineMocks defines the mocks shared by the load test (scripts/mocks-test.js) and the standalone server (serve.js).
+// The load test uses the default ports, the standalone server passes fixed ones.
+export function defineMocks(ports = {}) {
+  mock('https://api.example.com', app => {
+    app.get('/greeting', (req, res) => {
+      console.log(`${req.method} ${req.path}`)
+      res.json({ greeting: 'Hello World!' })
+    })
+  }, { port: ports.api })
+
+  mock('https://cdn.example.com', app => {
+    app.get('/lib.js', (req, res) => {
+      console.log(`${req.method} ${req.path}`)
+      res.text('window.lib = {}')
+    })
+  }, { port: ports.cdn })
+}
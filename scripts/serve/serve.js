@@ -0,0 +1,26 @@
+import { sleep } from 'k6'
+import { defineMocks } from './mocks.js'
+
+export const options = {
+  vus: 1,
+  duration: __ENV.DURATION || '24h',
+  ext: {
+    mock: {
+      // the default function blocks in sleep(), handlers must not wait for the event loop
+      sync: true
+    }
+  }
+}
+
+// the mock definitions of the load test, served on fixed ports; only the first VU serves,
+// the VU used for parsing options would occupy the ports
+if (__VU == 1) {
+  defineMocks({
+    api: parseInt(__ENV.API_PORT || '3000'),
+    cdn: parseInt(__ENV.CDN_PORT || '3001')
+  })
+}
+
+export default function () {
+  sleep(1)
+}